@@ -5,42 +5,240 @@ package hqgohttp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
-	dac "github.com/Mzack9999/go-http-digest-auth-client"
+	"github.com/hueristiq/hqgohttp/headers"
 	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/http2"
+	"golang.org/x/sync/semaphore"
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrByteBudgetExceeded is returned by Do once the client has read Options.MaxTotalBytes bytes.
+var ErrByteBudgetExceeded = errors.New("byte budget exceeded")
+
+// GiveUpError is returned by Do when retries are exhausted.
+type GiveUpError struct {
+	// Method and URL identify the request given up on.
+	Method string
+	URL    string
+	// Tries is the number of attempts made.
+	Tries int
+	// Attempts holds the errors of the failed attempts, oldest first, keeping at most the retry
+	// max of the request plus one.
+	Attempts []error
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *GiveUpError) Error() string {
+	return fmt.Sprintf("%s %s giving up after %d attempts: %v", e.Method, e.URL, e.Tries, e.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *GiveUpError) Unwrap() error {
+	return e.Err
+}
+
 // Options represents configuration fields to customize the behavior of the HTTP client
 type Options struct {
 	// Custom http client
 	HTTPClient *http.Client
+	// SharedTransport is a transport used as is, to share one connection pool across clients
+	SharedTransport *http.Transport
 	// KillIdleConn specifies if all keep-alive connections gets killed
 	KillIdleConn bool
+	// IdleConnTimeout is how long idle connections are kept in the pool. Defaults to 90 seconds
+	IdleConnTimeout time.Duration
+	// ConnHealthCheckInterval, when positive, closes the pooled connections idle for that long, until Close
+	ConnHealthCheckInterval time.Duration
+	// MaxRequestsPerConn, when positive, closes connections once they served that many requests
+	MaxRequestsPerConn int
 	// RespReadLimit is the maximum HTTP response size to read for connection being reused.
 	RespReadLimit int64
 	// Timeout is the maximum time to wait for the request
 	Timeout time.Duration
 	// NoAdjustTimeout disables automatic adjustment of HTTP request timeout
 	NoAdjustTimeout bool
+	// TimeoutAdjustFactor is the share of Timeout each attempt gets when it is adjusted, 0.3 by
+	// default. It is logged with Verbose only, so that New doesn't write to the log unasked
+	TimeoutAdjustFactor float64
+	// TCPKeepAlive is the interval between TCP keep-alive probes. Zero uses the default of 30 seconds,
+	// a negative value disables keep-alive probes.
+	TCPKeepAlive time.Duration
+	// IPVersion restricts connections to IPv4 or IPv6. Defaults to DualStack.
+	IPVersion IPVersion
+	// LocalAddr binds outgoing connections to a local address, and Interface to an address of a
+	// network interface. New fails with ErrInvalidLocalAddr for the ones it can't use
+	LocalAddr net.Addr
+	Interface string
+	// DialContext replaces the client's dialer, TLS, HTTP/2 and proxy settings still applying
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	// DisableHTTP2Fallback disables retrying over native HTTP/2 requests answered with HTTP/2
+	DisableHTTP2Fallback bool
+	// ForceHTTP2 sends all requests through the native HTTP/2 client from the start.
+	ForceHTTP2 bool
+	// ForceHTTP10 sends requests as HTTP/1.0 through a minimal transport, without proxy, HTTP/2 or keep-alive
+	ForceHTTP10 bool
+	// RawHeaderOrder lists the header names written first, in order, and PreserveHeaderCase keeps
+	// the case of header names. Both send HTTP/1.x requests through the transport of ForceHTTP10
+	RawHeaderOrder     []string
+	PreserveHeaderCase bool
+	// ProxyURL is the URL of a proxy to send all requests through, instead of the environment's
+	ProxyURL string
+	// NoProxy lists the hosts, domains, IP addresses and CIDR ranges dialed directly, as NO_PROXY does
+	NoProxy []string
+	// DefaultHeaders are set on every request that doesn't set them itself.
+	DefaultHeaders http.Header
+	// PerHostHeaders are set on the requests to the host ("host" or "host:port") they are keyed
+	// by, unless the request sets them itself, over DefaultHeaders
+	PerHostHeaders map[string]http.Header
+	// GenerateRequestID makes Do set a unique ID on every request without one, also tagged "request-id"
+	GenerateRequestID bool
+	// RequestIDHeader is the header holding the request ID. Defaults to X-Request-ID.
+	RequestIDHeader string
+	// SingleFlight coalesces concurrent identical GET and HEAD requests, each getting a copy of the response
+	SingleFlight bool
 
 	// Custom CheckRetry policy
 	CheckRetry CheckRetry
+	// Custom CheckRetryFull policy, preferred over CheckRetry when set
+	CheckRetryFull CheckRetryFull
 	// RetryMax is the maximum number of retries
 	RetryMax int
 	// Custom Backoff policy
 	Backoff Backoff
+	// Custom LatencyBackoff policy, preferred over Backoff when set
+	LatencyBackoff LatencyBackoff
 	// RetryWaitMin is the minimum time to wait for retry
 	RetryWaitMin time.Duration
 	// RetryWaitMax is the maximum time to wait for retry
 	RetryWaitMax time.Duration
+	// BackoffScheduler, when set, rounds the backoff waits up to its next tick, retrying in waves
+	BackoffScheduler *BackoffScheduler
+	// Clock is the source of time used for backoff waits and timeouts. Defaults to the real clock.
+	Clock Clock
+
+	// MaxTotalBytes is the maximum number of response bytes read over the client's lifetime,
+	// past which Do fails with ErrByteBudgetExceeded. Zero means no limit.
+	MaxTotalBytes int64
+	// GlobalDeadline and MaxRuntime, from the creation of the client, time box all its requests
+	GlobalDeadline time.Time
+	MaxRuntime     time.Duration
+
+	// URLRewriter rewrites a copy of the URL of every request before it is sent, the Host header
+	// following it with URLRewriteHost
+	URLRewriter    func(u *url.URL) *url.URL
+	URLRewriteHost bool
+
+	// BeforeRetry is called before every retry with the attempt number, and may change the request
+	BeforeRetry func(req *http.Request, attempt int) error
+	// PreSendHook is called right before every attempt is sent, and may change the request
+	PreSendHook func(req *http.Request) error
+	// SuccessErrors lists predicates of the errors Do returns as is, without retrying them
+	SuccessErrors []func(err error) bool
+	// HARWriter receives every completed request as a HAR 1.2 entry, one JSON object per line
+	HARWriter io.Writer
+	// MaxConcurrentRequests caps the requests in flight, each holding a slot until its body is closed
+	MaxConcurrentRequests int
+	// BufferSize is the size of the pooled buffers draining response bodies. Defaults to 32KB.
+	BufferSize int
+	// ReturnLastResponse makes Do return the last response along with the GiveUpError
+	ReturnLastResponse bool
+	// AutoReferer drops the Referer net/http sets on redirects when the Referrer-Policy is no-referrer
+	AutoReferer bool
+	// Minimal makes Do send each request once, without retries, hooks or the HTTP/2 fallback.
+	// Timeout, transport, redirect, header and concurrency options still apply.
+	Minimal bool
+	// ResponseHook may transform or replace the responses Do succeeds with, an error failing Do.
+	// A hook replacing the response or its body owns the closing of the original.
+	ResponseHook func(res *http.Response) (*http.Response, error)
+	// InterceptionHook is called with the responses that look intercepted, see DetectInterception
+	InterceptionHook func(res *http.Response, reason string)
+	// MaxBytesPerSecond caps the throughput of request and response bodies. Zero means no limit.
+	MaxBytesPerSecond int64
+	// PinnedPublicKeySHA256 lists the digests of the public keys trusted instead of the system CA
+	// store, as returned by PublicKeySHA256
+	PinnedPublicKeySHA256 []string
+	// MinTLSVersion and MaxTLSVersion bound the TLS versions of connections
+	MinTLSVersion uint16
+	MaxTLSVersion uint16
+	// DrainTimeout bounds the time spent draining a response body between retries
+	DrainTimeout time.Duration
+	// MaxConcurrentRetries caps the number of requests being retried. Zero means no limit.
+	MaxConcurrentRetries int
+	// SkipRetriesOverLimit makes requests give up rather than wait for a MaxConcurrentRetries slot
+	SkipRetriesOverLimit bool
+	// GlobalConcurrencyLimiter caps the work in flight of the helpers spawning goroutines or holding streams
+	GlobalConcurrencyLimiter *semaphore.Weighted
+	// RetryBudget caps the ratio of retries to requests across the client. Zero means no budget.
+	RetryBudget float64
+	// RedirectStatusCodes lists the redirect status codes to follow. Defaults to all of them.
+	RedirectStatusCodes []int
+	// RedirectPreserveMethod lists the redirect status codes keeping the method and body of the request
+	RedirectPreserveMethod []int
+	// MaxRedirectDuration caps the time spent following the redirects of an attempt
+	MaxRedirectDuration time.Duration
+	// StrictRedirects makes Do fail with ErrRedirectWithoutLocation on redirects without Location
+	StrictRedirects bool
+
+	// ErrorOnEmptyBody makes Do fail with an *EmptyBodyError on 2xx GET and POST responses without body
+	ErrorOnEmptyBody bool
+	// ComputeBodyHash stores the SHA-256 hash of the response body in Metrics.BodyHash once read
+	ComputeBodyHash bool
+	// AutoDecompress decodes response bodies according to their Content-Encoding, lists included
+	AutoDecompress bool
+	// MaxCompressionRatio bounds the ratio of decoded to compressed size of AutoDecompress bodies
+	MaxCompressionRatio float64
+	// Limits are the size limits of requests and responses.
+	Limits Limits
+	// MaxLineLength bounds the length of the lines read by StreamLines. Defaults to 64KB.
+	MaxLineLength int
+	// StreamReconnect makes StreamLines send the request again when the stream breaks
+	StreamReconnect bool
+	// RetryOnBodyError resumes the bodies of GET responses cut short, with range requests
+	RetryOnBodyError bool
+	// LongPollInterval is the minimum interval between the starts of the requests of LongPoll.
+	LongPollInterval time.Duration
+	// FollowMetaRefresh follows the meta refresh redirects of HTML responses delayed up to
+	// MetaRefreshMaxDelay, 5 seconds by default
+	FollowMetaRefresh   bool
+	MetaRefreshMaxDelay time.Duration
+	// SniffContentType detects the Content-Type of the request bodies sent without one
+	SniffContentType bool
+	// OnEarlyHints is called with the headers of the 103 Early Hints responses received
+	OnEarlyHints func(header http.Header)
+	// DeduplicateHeaders removes the duplicate request header names and values before sending
+	DeduplicateHeaders bool
+	// RequestDelay and RequestDelayJitter delay the first attempt of every request
+	RequestDelay       time.Duration
+	RequestDelayJitter time.Duration
+	// AdaptToRateLimit delays the requests to hosts whose rate limit is exhausted until it resets
+	AdaptToRateLimit bool
+	// DefaultScheme is prepended to the schemeless URLs given to the client's helpers
+	DefaultScheme string
+	// BaseURLs are the base URLs of equivalent endpoints DoWithFailover fails over across
+	BaseURLs []string
+
+	// LatencyRecorder records the latency of every Do call, and of its phases
+	LatencyRecorder *LatencyRecorder
+
+	// TracerProvider traces every Do call as an OpenTelemetry client span
+	TracerProvider trace.TracerProvider
 
 	// Verbose specifies if debug messages should be printed
 	Verbose bool
@@ -61,10 +259,53 @@ type Client struct {
 	ErrorHandler ErrorHandler
 	// CheckRetry specifies the policy for handling retries, and is called after each request
 	CheckRetry CheckRetry
+	// CheckRetryFull is like CheckRetry, but is also given the request.
+	// It takes precedence over CheckRetry when set.
+	CheckRetryFull CheckRetryFull
 	// Backoff specifies the policy for how long to wait between retries
 	Backoff Backoff
+	// LatencyBackoff is like Backoff, but also given how long the failed attempt took.
+	// It takes precedence over Backoff when set.
+	LatencyBackoff LatencyBackoff
+
+	clock Clock
+
+	deadline time.Time
+
+	flights singleflight.Group
+
+	requestSlots *semaphore.Weighted
+	retrySlots   *semaphore.Weighted
+
+	retryBudget *retryBudget
+
+	robots *RobotsPolicy
+
+	failover *failover
+
+	connRequests connRequests
+
+	idleConns idleConns
 
-	requestCounter uint32
+	rateLimits rateLimits
+
+	harMutex sync.Mutex
+
+	buffers sync.Pool
+
+	http11     *http.Client
+	http11Once sync.Once
+
+	insecureClients map[*http.Client]*http.Client
+	insecureMutex   sync.Mutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	requestCounter   uint32
+	totalRequests    uint64
+	activeRequests   int64
+	bytesTransferred int64
 
 	options Options
 }
@@ -82,47 +323,394 @@ func (c *Client) setKillIdleConnections() {
 
 // Do wraps calling an HTTP method with retries.
 func (c *Client) Do(req *Request) (res *http.Response, err error) {
-	// Create a main context that will be used as the main timeout
-	mainCtx, cancel := context.WithTimeout(context.Background(), c.options.Timeout)
+	atomic.AddUint64(&c.totalRequests, 1)
 
-	defer cancel()
+	atomic.AddInt64(&c.activeRequests, 1)
+	defer atomic.AddInt64(&c.activeRequests, -1)
 
-	retryMax := c.options.RetryMax
+	// Make the request able to hold the values set by the hooks
+	req.values()
 
-	if ctxRetryMax := req.Context().Value(RetryMax); ctxRetryMax != nil {
-		if maxRetriesParsed, ok := ctxRetryMax.(int); ok {
-			retryMax = maxRetriesParsed
+	// Span the whole call, so that the requests turned down before being sent are traced too
+	endSpan := c.startSpan(req)
+
+	defer func() {
+		endSpan(res, err)
+	}()
+
+	deadlineDone, err := c.applyGlobalDeadline(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		deadlineDone(res, err)
+	}()
+
+	if c.options.MaxTotalBytes > 0 && c.BytesTransferred() >= c.options.MaxTotalBytes {
+		return nil, ErrByteBudgetExceeded
+	}
+
+	restoreURL, err := c.rewriteURL(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer restoreURL()
+
+	if err = c.followRobots(req); err != nil {
+		return nil, err
+	}
+
+	defer c.recordLatencies(req)()
+
+	if err = c.checkRequestSize(req); err != nil {
+		return nil, err
+	}
+
+	res, err = c.dispatch(req)
+
+	if err == nil && res != nil && c.options.StrictRedirects {
+		if err = c.checkRedirectLocation(req, res); err != nil {
+			return nil, err
+		}
+	}
+
+	if err == nil && res != nil && c.options.FollowMetaRefresh {
+		res, err = c.followMetaRefresh(req, res)
+	}
+
+	if err == nil && res != nil {
+		if res, err = c.limitResponse(res); err != nil {
+			return nil, err
+		}
+	}
+
+	if err == nil && res != nil {
+		c.detectInterception(req, res)
+	}
+
+	if err == nil && res != nil && c.options.ErrorOnEmptyBody {
+		if err = checkEmptyBody(res); err != nil {
+			return nil, err
+		}
+	}
+
+	if err == nil && res != nil && c.options.ResponseHook != nil {
+		hooked, hookErr := c.options.ResponseHook(res)
+
+		// Don't leak the body of a response failed by the hook, unless the hook replaced it
+		if hookErr != nil && (hooked == nil || hooked == res) {
+			closeBody(res)
+
+			return nil, hookErr
+		}
+
+		return hooked, hookErr
+	}
+
+	return
+}
+
+// delayRequest waits RequestDelay plus a random share of RequestDelayJitter before the first
+// attempt of the request, or until the request context is done.
+func (c *Client) delayRequest(req *Request) (err error) {
+	delay := c.options.RequestDelay
+
+	if c.options.RequestDelayJitter > 0 {
+		delay += time.Duration(cryptoRandInt(int(c.options.RequestDelayJitter)))
+	}
+
+	if delay <= 0 {
+		return
+	}
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-c.clock.After(delay):
+	}
+
+	return
+}
+
+// dispatch sends the request the way the client is configured to.
+func (c *Client) dispatch(req *Request) (res *http.Response, err error) {
+	switch {
+	case c.options.Minimal:
+		return c.doMinimal(req)
+	case c.options.SingleFlight && isCoalescable(req):
+		return c.doShared(req)
+	default:
+		return c.do(req)
+	}
+}
+
+// DoTimed is like Do, but also returns how long the call took, retries and backoff waits included.
+func (c *Client) DoTimed(req *Request) (res *http.Response, elapsed time.Duration, err error) {
+	start := c.clock.Now()
+
+	res, err = c.Do(req)
+
+	elapsed = c.clock.Now().Sub(start)
+
+	return
+}
+
+// doMinimal sends the request once, without hooks, retries or fallbacks.
+func (c *Client) doMinimal(req *Request) (res *http.Response, err error) {
+	c.setDefaultHeaders(req)
+
+	c.acceptGzip(req)
+
+	if err = c.sniffContentType(req); err != nil {
+		return
+	}
+
+	c.rotateConns(req)
+
+	c.trackIdleConns(req)
+
+	c.traceEarlyHints(req)
+
+	if c.options.DeduplicateHeaders {
+		deduplicateHeaders(req.Header, c.options.PreserveHeaderCase)
+	}
+
+	if err = c.delayRequest(req); err != nil {
+		return
+	}
+
+	if err = c.waitRateLimit(req); err != nil {
+		return
+	}
+
+	req.Metrics.BytesSent += requestSize(req.Request)
+
+	c.startRedirectChain(req)
+
+	if err = c.acquireRequestSlot(req.Context()); err != nil {
+		return
+	}
+
+	res, err = c.tlsClient(req, c.HTTPClient).Do(req.Request)
+
+	c.holdRequestSlot(res)
+
+	c.recordRateLimit(req, res)
+
+	recordProtocol(req, res)
+
+	err = tlsVersionError(err)
+	err = responseHeaderError(err)
+
+	c.closeIdleConnections()
+
+	c.resumeBody(req, res)
+
+	c.countBody(req, res)
+
+	c.decompressBody(req, res)
+
+	c.hashBody(req, res)
+
+	return
+}
+
+// do executes the request, retrying it according to the client's policies.
+func (c *Client) do(req *Request) (res *http.Response, err error) {
+	c.setDefaultHeaders(req)
+
+	c.acceptGzip(req)
+
+	if err = c.sniffContentType(req); err != nil {
+		return
+	}
+
+	c.rotateConns(req)
+
+	c.trackIdleConns(req)
+
+	c.traceEarlyHints(req)
+
+	harTrace := c.traceHAR(req)
+
+	if c.options.GenerateRequestID {
+		if err = c.setRequestID(req); err != nil {
+			return
 		}
 	}
 
+	if c.retryBudget != nil {
+		c.retryBudget.deposit()
+	}
+
+	if err = c.delayRequest(req); err != nil {
+		return
+	}
+
+	if err = c.waitRateLimit(req); err != nil {
+		return
+	}
+
+	// Create a main timer that will be used as the main timeout
+	mainTimer := c.clock.NewTimer(c.options.Timeout)
+
+	defer mainTimer.Stop()
+
+	mainTimeout := mainTimer.C()
+
+	retryMax := c.getRetryMax(req)
+
+	HTTPClient := c.HTTPClient
+
+	if c.options.ForceHTTP2 {
+		HTTPClient = c.HTTP2Client
+	}
+
+	if override, ok := req.Context().Value(httpClientKey{}).(*http.Client); ok {
+		HTTPClient = override
+	}
+
+	HTTPClient = c.tlsClient(req, HTTPClient)
+
+	// checkCtx exposes the read limit to body-aware retry policies
+	checkCtx := context.WithValue(req.Context(), respReadLimitKey{}, c.options.RespReadLimit)
+
+	// attempts collects per-attempt errors, bounded to keep memory in check
+	var attempts []error
+
+	attemptsMax := c.getRetryMax(req) + 1
+
 	for i := 0; ; i++ {
-		// request body can be read multiple times hence no need to rewind it
+		// reusable request bodies can be read multiple times, others (e.g files)
+		// are reopened through GetBody before each retry
+		if i > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		if i > 0 {
+			traceRetry(req, i)
+		}
+
+		// Let the caller refresh the request, e.g. its nonce or signature, once the body is rewound
+		if i > 0 && c.options.BeforeRetry != nil {
+			if err = c.options.BeforeRetry(req.Request, i); err != nil {
+				return nil, err
+			}
+		}
+
+		c.throttleRequestBody(req)
+
 		if c.RequestLogHook != nil {
 			c.RequestLogHook(req.Request, i)
 		}
 
-		if req.hasAuth() && req.Auth.Type == DigestAuth {
-			digestTransport := dac.NewTransport(req.Auth.Username, req.Auth.Password)
-			digestTransport.HTTPClient = c.HTTPClient
-			res, err = digestTransport.RoundTrip(req.Request)
+		// Wait for a free slot when concurrent requests are capped
+		if err = c.acquireRequestSlot(req.Context()); err != nil {
+			return nil, err
+		}
+
+		if c.options.DeduplicateHeaders {
+			deduplicateHeaders(req.Header, c.options.PreserveHeaderCase)
+		}
+
+		// Run the pre-send hook last, so that it sees the request exactly as sent
+		if c.options.PreSendHook != nil {
+			if err = c.options.PreSendHook(req.Request); err != nil {
+				c.releaseRequestSlot()
+
+				return nil, err
+			}
+		}
+
+		attemptStart := c.clock.Now()
+
+		c.startRedirectChain(req)
+
+		req.Metrics.BytesSent += requestSize(req.Request)
+
+		if req.hasAuth() {
+			// Answer the server's authentication challenge with the request's credentials
+			res, err = c.sendAuthenticated(HTTPClient, req)
 		} else {
 			// Attempt the request with standard behavior
-			res, err = c.HTTPClient.Do(req.Request)
+			res, err = HTTPClient.Do(req.Request)
 		}
 
+		err = tlsVersionError(err)
+		err = responseHeaderError(err)
+
 		// Check if we should continue with retries.
-		checkOK, checkErr := c.CheckRetry(req.Context(), res, err)
+		checkOK, checkErr := c.checkRetry(checkCtx, req, res, err)
 
 		// if err is equal to missing minor protocol version retry with http/2
-		if err != nil && strings.Contains(err.Error(), "net/http: HTTP/1.x transport connection broken: malformed HTTP version \"HTTP/2\"") {
-			res, err = c.HTTP2Client.Do(req.Request)
+		if err != nil && c.useHTTP2Fallback() && strings.Contains(err.Error(), "net/http: HTTP/1.x transport connection broken: malformed HTTP version \"HTTP/2\"") {
+			if req.GetBody != nil {
+				if req.Body, err = req.GetBody(); err != nil {
+					c.releaseRequestSlot()
+
+					return nil, err
+				}
+			}
+
+			req.Metrics.BytesSent += requestSize(req.Request)
 
-			checkOK, checkErr = c.CheckRetry(req.Context(), res, err)
+			res, err = c.tlsClient(req, c.HTTP2Client).Do(req.Request)
+
+			checkOK, checkErr = c.checkRetry(checkCtx, req, res, err)
+		}
+
+		// retry over HTTP/1.1 when a flaky HTTP/2 server sends GOAWAY or refuses the stream
+		if err != nil && c.useHTTP2Downgrade() && isHTTP2DowngradeError(err) {
+			if HTTP11Client := c.http11Client(); HTTP11Client != nil {
+				if req.GetBody != nil {
+					if req.Body, err = req.GetBody(); err != nil {
+						c.releaseRequestSlot()
+
+						return nil, err
+					}
+				}
+
+				req.Metrics.HTTP2Downgrades++
+				req.Metrics.BytesSent += requestSize(req.Request)
+
+				res, err = c.tlsClient(req, HTTP11Client).Do(req.Request)
+
+				checkOK, checkErr = c.checkRetry(checkCtx, req, res, err)
+			}
+		}
+
+		// Keep the slot until the response body is closed, be it by the caller or when draining it
+		c.holdRequestSlot(res)
+
+		c.recordRateLimit(req, res)
+
+		recordProtocol(req, res)
+
+		req.Metrics.Class = ClassifyResponse(res, err)
+
+		// Expected errors are outcomes, return them as is without retrying
+		if err != nil && c.isExpectedError(err) {
+			req.Metrics.ExpectedError = true
+
+			c.closeIdleConnections()
+
+			return nil, err
 		}
 
 		if err != nil {
 			// Increment the failure counter as the request failed
 			req.Metrics.Failures++
+
+			// Keep the errors of the most recent attempts for the give-up error
+			attempts = append(attempts, err)
+
+			if len(attempts) > attemptsMax {
+				attempts = attempts[1:]
+			}
 		} else if c.ResponseLogHook != nil {
 			// Call this here to maintain the behavior of logging all requests,
 			// even if CheckRetry signals to stop.
@@ -139,6 +727,20 @@ func (c *Client) Do(req *Request) (res *http.Response, err error) {
 
 			c.closeIdleConnections()
 
+			c.resumeBody(req, res)
+
+			c.countBody(req, res)
+
+			c.decompressBody(req, res)
+
+			c.hashBody(req, res)
+
+			if c.options.HARWriter != nil && res != nil {
+				c.writeHAREntry(req, res, harTrace, attemptStart, c.clock.Now().Sub(attemptStart))
+			}
+
+			c.throttleResponseBody(req.Context(), res)
+
 			return res, err
 		}
 
@@ -149,6 +751,34 @@ func (c *Client) Do(req *Request) (res *http.Response, err error) {
 			break
 		}
 
+		// Wait for the time specified by backoff then retry, within the request deadline.
+		// If the context is cancelled however, return.
+		wait := c.backoff(i, res, c.clock.Now().Sub(attemptStart))
+
+		if c.options.BackoffScheduler != nil {
+			wait = c.options.BackoffScheduler.align(c.clock.Now(), wait)
+		}
+
+		wait, ok := c.capBackoff(req.Context(), wait)
+		if !ok {
+			break
+		}
+
+		// Give up when the retry phase is full and the request shouldn't wait for it
+		if i == 0 && !c.tryRetrySlot() {
+			break
+		}
+
+		// Give up when the retry budget is exhausted
+		if c.retryBudget != nil && !c.retryBudget.withdraw() {
+			// Hand back the slot tryRetrySlot took, waitRetrySlot takes none then
+			if i == 0 && c.options.SkipRetriesOverLimit {
+				c.releaseRetrySlot()
+			}
+
+			break
+		}
+
 		// Increment the retries counter as we are going to do one more retry
 		req.Metrics.Retries++
 
@@ -157,29 +787,63 @@ func (c *Client) Do(req *Request) (res *http.Response, err error) {
 			c.drainBody(req, res)
 		}
 
-		// Wait for the time specified by backoff then retry.
-		// If the context is cancelled however, return.
-		wait := c.Backoff(c.options.RetryWaitMin, c.options.RetryWaitMax, i, res)
+		// Enter the retry phase, holding a retry slot until the request is done. The response
+		// is drained first, so that its request slot isn't held while waiting.
+		if i == 0 {
+			if err = c.waitRetrySlot(req.Context()); err != nil {
+				c.closeIdleConnections()
+
+				return nil, err
+			}
+
+			defer c.releaseRetrySlot()
+		}
 
-		// Exit if the main context or the request context is done
+		// Exit if the main timer fired or the request context is done
 		// Otherwise, wait for the duration and try again.
 		// use label to explicitly specify what to break
 		select {
-		case <-mainCtx.Done(): // Do nothing; it will break out of the select block by default.
+		case <-mainTimeout: // Do nothing; it will break out of the select block by default.
+			// The timer fires only once, keep later waits from blocking as an expired context would.
+			mainTimeout = expired
 		case <-req.Context().Done():
 			c.closeIdleConnections()
 
 			return nil, req.Context().Err()
-		case <-time.After(wait): // Do nothing; it will continue after the wait duration.
+		case <-c.clock.After(wait): // Do nothing; it will continue after the wait duration.
 		}
 	}
 
 	if c.ErrorHandler != nil {
 		c.closeIdleConnections()
 
+		c.resumeBody(req, res)
+
+		c.countBody(req, res)
+
+		c.decompressBody(req, res)
+
+		c.hashBody(req, res)
+
 		return c.ErrorHandler(res, err, retryMax+1)
 	}
 
+	giveUpErr := &GiveUpError{
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Tries:    retryMax + 1,
+		Attempts: attempts,
+		Err:      err,
+	}
+
+	if c.options.ReturnLastResponse && res != nil {
+		c.closeIdleConnections()
+
+		c.snapshotBody(res)
+
+		return res, giveUpErr
+	}
+
 	// By default, we close the response body and return an error without
 	// returning the response
 	if res != nil {
@@ -188,17 +852,197 @@ func (c *Client) Do(req *Request) (res *http.Response, err error) {
 
 	c.closeIdleConnections()
 
-	return nil, fmt.Errorf("%s %s giving up after %d attempts: %w", req.Method, req.URL, retryMax+1, err)
+	return nil, giveUpErr
+}
+
+// setDefaultHeaders sets the per-host and default headers the request doesn't set itself.
+func (c *Client) setDefaultHeaders(req *Request) {
+	if len(c.options.PerHostHeaders) > 0 {
+		hostHeaders, ok := c.options.PerHostHeaders[req.URL.Host]
+		if !ok {
+			hostHeaders = c.options.PerHostHeaders[req.URL.Hostname()]
+		}
+
+		mergeHeaders(req.Header, hostHeaders)
+	}
+
+	mergeHeaders(req.Header, c.options.DefaultHeaders)
+}
+
+// setRequestID sets a unique request ID header on the request unless it already has one,
+// and tags the request with it.
+func (c *Client) setRequestID(req *Request) (err error) {
+	header := c.options.RequestIDHeader
+
+	if header == "" {
+		header = headers.XRequestID
+	}
+
+	ID := req.Header.Get(header)
+
+	if ID == "" {
+		if ID, err = newRequestID(); err != nil {
+			return
+		}
+
+		req.Header.Set(header, ID)
+	}
+
+	req.WithTag(requestIDTag, ID)
+
+	return
+}
+
+// backoff returns how long to wait before retrying, given the attempt number,
+// its response and how long it took.
+func (c *Client) backoff(attemptNum int, res *http.Response, elapsed time.Duration) time.Duration {
+	if c.LatencyBackoff != nil {
+		return c.LatencyBackoff(c.options.RetryWaitMin, c.options.RetryWaitMax, attemptNum, res, elapsed)
+	}
+
+	return c.Backoff(c.options.RetryWaitMin, c.options.RetryWaitMax, attemptNum, res)
+}
+
+// capBackoff caps wait so that the next attempt starts early enough to complete before the
+// context deadline, if any. ok is false when too little time remains for another attempt,
+// as the deadline would only expire mid-wait or mid-attempt.
+func (c *Client) capBackoff(ctx context.Context, wait time.Duration) (capped time.Duration, ok bool) {
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		return wait, true
+	}
+
+	remaining := deadline.Sub(c.clock.Now()) - minAttemptWindow
+	if remaining <= 0 {
+		return 0, false
+	}
+
+	if wait > remaining {
+		wait = remaining
+	}
+
+	return wait, true
+}
+
+// useHTTP2Fallback reports whether failed HTTP/1.x requests may be retried over native HTTP/2.
+func (c *Client) useHTTP2Fallback() bool {
+	return !c.options.DisableHTTP2Fallback && !c.options.ForceHTTP2 && !c.options.ForceHTTP10 && c.HTTP2Client != nil
+}
+
+// getRetryMax returns the maximum number of retries for the request,
+// honoring any override set in the request context with WithRetryMax.
+func (c *Client) getRetryMax(req *Request) (retryMax int) {
+	retryMax = c.options.RetryMax
+
+	if ctxRetryMax, ok := req.Context().Value(retryMaxKey{}).(int); ok {
+		return ctxRetryMax
+	}
+
+	// Honor the deprecated string key for backward compatibility
+	if ctxRetryMax := req.Context().Value(RetryMax); ctxRetryMax != nil {
+		if maxRetriesParsed, ok := ctxRetryMax.(int); ok {
+			retryMax = maxRetriesParsed
+		}
+	}
+
+	return
 }
 
 // Try to read the response body so we can reuse this connection.
+//
+// Chunked bodies (without Content-Length) can only be reused once read until their
+// terminating chunk. If such a body is larger than RespReadLimit, it is abandoned:
+// closing it unread makes the transport discard the connection instead of reusing it.
+//
+// The drain gives up when the request context is done or after DrainTimeout, so a
+// half-open connection can't hang the retry loop: the body is closed unread, which
+// discards the connection as well.
 func (c *Client) drainBody(req *Request, resp *http.Response) {
-	_, err := io.Copy(io.Discard, io.LimitReader(resp.Body, c.options.RespReadLimit))
-	if err != nil {
-		req.Metrics.DrainErrors++
+	type drained struct {
+		n         int64
+		err       error
+		abandoned bool
+	}
+
+	done := make(chan drained, 1)
+
+	go func() {
+		var result drained
+
+		result.n, result.err = c.discard(io.LimitReader(resp.Body, c.options.RespReadLimit))
+
+		if result.err == nil && isChunked(resp) {
+			// Probe for the end of the body, the limit may have been hit right before it.
+			var probe [1]byte
+
+			m, probeErr := resp.Body.Read(probe[:])
+
+			result.n += int64(m)
+			result.abandoned = !errors.Is(probeErr, io.EOF)
+		}
+
+		done <- result
+	}()
+
+	var timeout <-chan time.Time
+
+	if c.options.DrainTimeout > 0 {
+		timer := c.clock.NewTimer(c.options.DrainTimeout)
+
+		defer timer.Stop()
+
+		timeout = timer.C()
 	}
 
+	var result drained
+
+	timedOut := false
+
+	select {
+	case result = <-done:
+	case <-req.Context().Done():
+		timedOut = true
+	case <-timeout:
+		timedOut = true
+	}
+
+	// Closing the body unblocks a stuck drain
 	resp.Body.Close()
+
+	if timedOut {
+		req.Metrics.DrainTimeouts++
+
+		// The read error is the one of the body closed under it
+		result = <-done
+		result.err = nil
+	}
+
+	if result.err != nil {
+		req.Metrics.DrainErrors++
+	}
+
+	if result.abandoned {
+		req.Metrics.DrainAbandons++
+	}
+
+	atomic.AddInt64(&c.bytesTransferred, result.n)
+
+	req.Metrics.BytesReceived += result.n
+}
+
+// isChunked reports whether the response body is sent without a known length.
+func isChunked(resp *http.Response) bool {
+	if resp.ContentLength < 0 {
+		return true
+	}
+
+	for _, encoding := range resp.TransferEncoding {
+		if strings.EqualFold(encoding, "chunked") {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (c *Client) closeIdleConnections() {
@@ -214,9 +1058,79 @@ func (c *Client) closeIdleConnections() {
 	}
 }
 
+// countBody wraps the body of a response returned to the caller so that the bytes
+// read from it count towards BytesTransferred and the request's Metrics.BytesReceived.
+func (c *Client) countBody(req *Request, res *http.Response) {
+	if res == nil || res.Body == nil {
+		return
+	}
+
+	res.Body = &countingReadCloser{
+		ReadCloser: res.Body,
+		onRead: func(n int64) {
+			atomic.AddInt64(&c.bytesTransferred, n)
+			req.Metrics.BytesReceived += n
+		},
+	}
+}
+
+// recordProtocol stores the ALPN protocol negotiated for res in the request's
+// Metrics.NegotiatedProtocol, empty for plaintext responses.
+func recordProtocol(req *Request, res *http.Response) {
+	if res == nil {
+		return
+	}
+
+	req.Metrics.NegotiatedProtocol = ""
+
+	if res.TLS != nil {
+		req.Metrics.NegotiatedProtocol = res.TLS.NegotiatedProtocol
+	}
+}
+
+// requestSize returns the size of the request as sent over HTTP/1.1: its request line,
+// headers and body. Headers added by the transport (e.g. Host or User-Agent) are not included.
+func requestSize(req *http.Request) (size int64) {
+	// "METHOD URI HTTP/1.1\r\n"
+	size = int64(len(req.Method) + 1 + len(req.URL.RequestURI()) + len(" HTTP/1.1\r\n"))
+
+	for key, values := range req.Header {
+		for _, value := range values {
+			// "Key: value\r\n"
+			size += int64(len(key) + 2 + len(value) + 2)
+		}
+	}
+
+	// Blank line ending the headers
+	size += 2
+
+	if req.ContentLength > 0 {
+		size += req.ContentLength
+	}
+
+	return
+}
+
+// BytesTransferred returns the number of response bytes read over the lifetime of the
+// client, including bytes drained between retries.
+func (c *Client) BytesTransferred() int64 {
+	return atomic.LoadInt64(&c.bytesTransferred)
+}
+
+// RequestsSinceLastIdleClose returns the number of requests completed since idle
+// connections were last closed. It only advances when KillIdleConn is enabled.
+func (c *Client) RequestsSinceLastIdleClose() uint32 {
+	return atomic.LoadUint32(&c.requestCounter)
+}
+
+// TotalRequests returns the number of calls to Do over the lifetime of the client.
+func (c *Client) TotalRequests() uint64 {
+	return atomic.LoadUint64(&c.totalRequests)
+}
+
 // Get is a convenience helper for doing simple GET requests.
 func (c *Client) Get(URL string) (*http.Response, error) {
-	req, err := NewRequest(methods.Get, URL, nil)
+	req, err := NewRequest(methods.Get, c.withDefaultScheme(URL), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +1140,7 @@ func (c *Client) Get(URL string) (*http.Response, error) {
 
 // Head is a convenience method for doing simple HEAD requests.
 func (c *Client) Head(URL string) (*http.Response, error) {
-	req, err := NewRequest(methods.Head, URL, nil)
+	req, err := NewRequest(methods.Head, c.withDefaultScheme(URL), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -236,7 +1150,7 @@ func (c *Client) Head(URL string) (*http.Response, error) {
 
 // Post is a convenience method for doing simple POST requests.
 func (c *Client) Post(URL, bodyType string, body interface{}) (*http.Response, error) {
-	req, err := NewRequest(methods.Post, URL, body)
+	req, err := NewRequest(methods.Post, c.withDefaultScheme(URL), body)
 	if err != nil {
 		return nil, err
 	}
@@ -252,7 +1166,146 @@ func (c *Client) PostForm(URL string, data url.Values) (*http.Response, error) {
 	return c.Post(URL, "application/x-www-form-urlencoded", strings.NewReader(data.Encode()))
 }
 
-const closeConnectionsCounter = 100
+// PostFile is a convenience method for uploading a file with a POST request. The file is
+// streamed rather than loaded in memory, and reopened for each retry.
+func (c *Client) PostFile(URL, bodyType, path string) (*http.Response, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot upload %s: %w", path, err)
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("cannot upload %s: is a directory", path)
+	}
+
+	req, err := NewRequest(methods.Post, c.withDefaultScheme(URL), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("cannot upload %s: %w", path, err)
+		}
+
+		return file, nil
+	}
+
+	if req.Body, err = req.GetBody(); err != nil {
+		return nil, err
+	}
+
+	req.ContentLength = info.Size()
+
+	req.Header.Set("Content-Type", bodyType)
+
+	return c.Do(req)
+}
+
+// PutIfMatch is a convenience method for doing conditional PUT requests (optimistic
+// concurrency): the resource is only replaced if its current ETag matches etag.
+// If it doesn't, the server answers 412 and a *PreconditionFailedError, matching
+// ErrPreconditionFailed and holding the current ETag if the server sent it, is returned.
+func (c *Client) PutIfMatch(URL, etag, bodyType string, body interface{}) (*http.Response, error) {
+	req, err := NewRequest(methods.Put, c.withDefaultScheme(URL), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set(headers.ContentType, bodyType)
+	req.Header.Set(headers.IfMatch, etag)
+
+	res, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == status.PreconditionFailed {
+		res.Body.Close()
+
+		return nil, &PreconditionFailedError{ETag: res.Header.Get(headers.ETag)}
+	}
+
+	return res, nil
+}
+
+// ErrPreconditionFailed is matched by the error returned when a conditional request gets a 412 response.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// PreconditionFailedError is returned when a conditional request gets a 412 response.
+type PreconditionFailedError struct {
+	// ETag is the current ETag of the resource, if the server returned it.
+	ETag string
+}
+
+func (e *PreconditionFailedError) Error() string {
+	if e.ETag == "" {
+		return ErrPreconditionFailed.Error()
+	}
+
+	return fmt.Sprintf("%s: current ETag is %s", ErrPreconditionFailed, e.ETag)
+}
+
+// Unwrap returns ErrPreconditionFailed.
+func (e *PreconditionFailedError) Unwrap() error {
+	return ErrPreconditionFailed
+}
+
+// PatchOp is a single operation of a JSON Patch document (RFC 6902).
+type PatchOp struct {
+	// Op is one of add, remove, replace, move, copy or test.
+	Op string `json:"op"`
+	// Path is the JSON Pointer the operation applies to.
+	Path string `json:"path"`
+	// From is the source JSON Pointer of move and copy operations.
+	From string `json:"from,omitempty"`
+	// Value is the value of add, replace and test operations.
+	Value interface{} `json:"value,omitempty"`
+}
+
+// PatchMergeJSON is a convenience method for doing PATCH requests with a
+// JSON Merge Patch (RFC 7386) document marshaled from v.
+func (c *Client) PatchMergeJSON(URL string, v interface{}) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.patch(URL, "application/merge-patch+json", body)
+}
+
+// PatchJSONPatch is a convenience method for doing PATCH requests with a
+// JSON Patch (RFC 6902) document made of ops.
+func (c *Client) PatchJSONPatch(URL string, ops []PatchOp) (*http.Response, error) {
+	body, err := json.Marshal(ops)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.patch(URL, "application/json-patch+json", body)
+}
+
+func (c *Client) patch(URL, bodyType string, body []byte) (*http.Response, error) {
+	req, err := NewRequest(methods.Patch, c.withDefaultScheme(URL), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", bodyType)
+
+	return c.Do(req)
+}
+
+const (
+	closeConnectionsCounter = 100
+	// defaultTimeoutAdjustFactor is the share of Timeout each attempt gets by default.
+	defaultTimeoutAdjustFactor = 0.3
+	// minAttemptWindow is the least time left before the request deadline worth retrying in.
+	minAttemptWindow = 10 * time.Millisecond
+
+	requestIDTag = "request-id"
+)
 
 // DefaultOptionsSingle is an instance of Options with default values suitable for
 // "host brute force" scenarios, where lots of requests need to be sent to a single
@@ -292,25 +1345,56 @@ func init() {
 // New creates a new client instance based on provided options.
 // It configures the internal HTTP clients, sets up HTTP/2 for the second client,
 // applies retry and backoff policies, and Adjusts client timeouts and
-// other settings based on the provided options.
+// other settings based on the provided options. Invalid options are reported
+// by Options.Validate.
 func New(options *Options) (client *Client, err error) {
+	if err = options.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err = localAddr(options); err != nil {
+		return nil, err
+	}
+
 	client = &Client{}
 
 	client.HTTPClient = DefaultHTTPClient()
 
 	if options.HTTPClient != nil {
 		client.HTTPClient = options.HTTPClient
+	} else if options.SharedTransport != nil {
+		// The shared transport is used as is, other clients may be using it concurrently
+		client.HTTPClient = &http.Client{Transport: options.SharedTransport}
+	} else if HTTPClientTransport, ok := client.HTTPClient.Transport.(*http.Transport); ok {
+		if err = configureTransport(HTTPClientTransport, options); err != nil {
+			return
+		}
 	}
 
-	client.HTTP2Client = DefaultHTTPClient()
+	if options.HTTPClient == nil {
+		client.HTTPClient.CheckRedirect = newCheckRedirect(options)
+	}
 
-	HTTP2ClientTransport, ok := client.HTTP2Client.Transport.(*http.Transport)
-	if !ok {
-		return
+	if (options.ForceHTTP10 || len(options.RawHeaderOrder) > 0 || options.PreserveHeaderCase) && options.HTTPClient == nil {
+		useHTTP1(client.HTTPClient, options)
 	}
 
-	if err = http2.ConfigureTransport(HTTP2ClientTransport); err != nil {
-		return
+	if (!options.DisableHTTP2Fallback || options.ForceHTTP2) && !options.Minimal {
+		client.HTTP2Client = DefaultHTTPClient()
+		client.HTTP2Client.CheckRedirect = newCheckRedirect(options)
+
+		HTTP2ClientTransport, ok := client.HTTP2Client.Transport.(*http.Transport)
+		if !ok {
+			return
+		}
+
+		if err = configureTransport(HTTP2ClientTransport, options); err != nil {
+			return
+		}
+
+		if err = http2.ConfigureTransport(HTTP2ClientTransport); err != nil {
+			return
+		}
 	}
 
 	client.CheckRetry = DefaultRetryPolicy() //nolint:bodyclose // To be refactored
@@ -319,27 +1403,72 @@ func New(options *Options) (client *Client, err error) {
 		client.CheckRetry = options.CheckRetry
 	}
 
+	client.CheckRetryFull = options.CheckRetryFull
+
 	client.Backoff = DefaultBackoff() //nolint:bodyclose // To be refactored
 
 	if options.Backoff != nil {
 		client.Backoff = options.Backoff
 	}
 
+	client.LatencyBackoff = options.LatencyBackoff
+
+	if options.MaxConcurrentRequests > 0 {
+		client.requestSlots = semaphore.NewWeighted(int64(options.MaxConcurrentRequests))
+	}
+
+	if options.MaxConcurrentRetries > 0 {
+		client.retrySlots = semaphore.NewWeighted(int64(options.MaxConcurrentRetries))
+	}
+
+	if options.RetryBudget > 0 {
+		client.retryBudget = newRetryBudget(options.RetryBudget)
+	}
+
+	client.clock = DefaultClock()
+
+	if options.Clock != nil {
+		client.clock = options.Clock
+	}
+
+	client.deadline = globalDeadline(options, client.clock.Now())
+
 	// add timeout to clients
 	if options.Timeout > 0 {
 		client.HTTPClient.Timeout = options.Timeout
-		client.HTTP2Client.Timeout = options.Timeout
+
+		if client.HTTP2Client != nil {
+			client.HTTP2Client.Timeout = options.Timeout
+		}
+	}
+
+	// if necessary adjusts per-request timeout proportionally to general timeout (30% by default)
+	timeoutAdjustFactor := options.TimeoutAdjustFactor
+
+	if timeoutAdjustFactor == 0 {
+		timeoutAdjustFactor = defaultTimeoutAdjustFactor
 	}
 
-	// if necessary adjusts per-request timeout proportionally to general timeout (30%)
-	if options.Timeout > time.Second*15 && options.RetryMax > 1 && !options.NoAdjustTimeout {
-		client.HTTPClient.Timeout = time.Duration(options.Timeout.Seconds()*0.3) * time.Second
+	if options.Timeout > time.Second*15 && options.RetryMax > 1 && !options.NoAdjustTimeout && !options.Minimal && timeoutAdjustFactor < 1 {
+		client.HTTPClient.Timeout = time.Duration(float64(options.Timeout) * timeoutAdjustFactor)
+
+		if options.Verbose {
+			log.Printf("hqgohttp: per-request timeout adjusted from %s to %s (TimeoutAdjustFactor %.2f), set NoAdjustTimeout to disable", options.Timeout, client.HTTPClient.Timeout, timeoutAdjustFactor)
+		}
+	}
+
+	if len(options.BaseURLs) > 0 {
+		client.failover = newFailover(options.BaseURLs)
 	}
 
 	client.options = *options
 
 	client.setKillIdleConnections()
 
+	if options.ConnHealthCheckInterval > 0 {
+		client.startHealthCheck(options.ConnHealthCheckInterval)
+	}
+
 	return
 }
 
@@ -0,0 +1,76 @@
+package hqgohttp
+
+// This file contains code for polling RFC 7240 asynchronous operations, answered with 202
+// Accepted and a Location to poll, until they complete.
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// DoAsync sends the request with `Prefer: respond-async`. If the server accepts to process it
+// asynchronously, answering 202 Accepted with a Location, that URL is polled with GET until it
+// answers anything but 202, and that final response is returned. Polls are pollInterval apart,
+// or follow the client's backoff schedule if pollInterval is zero, unless the server says
+// otherwise with Retry-After. Polls carry the headers of the request, and stop with its context.
+func (c *Client) DoAsync(req *Request, pollInterval time.Duration) (res *http.Response, err error) {
+	req.Header.Set(headers.Prefer, "respond-async")
+
+	if res, err = c.Do(req); err != nil {
+		return
+	}
+
+	for poll := 0; res.StatusCode == status.Accepted; poll++ {
+		location := res.Header.Get(headers.Location)
+
+		if location == "" {
+			return
+		}
+
+		target, parseErr := res.Request.URL.Parse(location)
+		if parseErr != nil {
+			return
+		}
+
+		wait := pollInterval
+
+		if wait <= 0 {
+			wait = c.backoff(poll, res, 0)
+		}
+
+		if retryAfter, ok := parseRetryAfter(res.Header.Get(headers.RetryAfter), c.clock.Now()); ok {
+			wait = retryAfter
+		}
+
+		c.drainBody(req, res)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-c.clock.After(wait):
+		}
+
+		var pollReq *Request
+
+		if pollReq, err = NewRequestWithContext(req.Context(), methods.Get, target.String(), nil); err != nil {
+			return nil, err
+		}
+
+		pollReq.Header = req.Header.Clone()
+		pollReq.Auth = req.Auth
+
+		// The polls have no body
+		pollReq.Header.Del(headers.ContentType)
+		pollReq.Header.Del(headers.Prefer)
+
+		if res, err = c.Do(pollReq); err != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,142 @@
+package hqgohttp
+
+// This file contains the WWW-Authenticate challenge-response flow: a request carrying credentials
+// is first sent without them, and re-sent once with an Authorization header when the server
+// challenges it with a matching scheme.
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	dac "github.com/Mzack9999/go-http-digest-auth-client"
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// sendAuthenticated sends the request, answering a 401 challenge of the scheme of the request's
+// Auth with a single authenticated retry. Digest challenges are answered by the digest transport,
+// which also computes the per-request digest. Requests with an explicit Authorization header are
+// sent as is.
+func (c *Client) sendAuthenticated(HTTPClient *http.Client, req *Request) (res *http.Response, err error) {
+	if req.Auth.Type == DigestAuth {
+		digestTransport := dac.NewTransport(req.Auth.Username, req.Auth.Password)
+		digestTransport.HTTPClient = HTTPClient
+
+		return digestTransport.RoundTrip(req.Request)
+	}
+
+	if res, err = HTTPClient.Do(req.Request); err != nil {
+		return
+	}
+
+	if res.StatusCode != status.Unauthorized || req.Header.Get(headers.Authorization) != "" {
+		return
+	}
+
+	if !hasChallenge(res.Header.Values(headers.WWWAuthenticate), req.Auth.Type.scheme()) {
+		return
+	}
+
+	// The request must be sent again, which requires a body that can be rewound
+	hasBody := req.Body != nil && req.Body != http.NoBody
+
+	if hasBody && req.GetBody == nil {
+		return
+	}
+
+	if hasBody {
+		var body io.ReadCloser
+
+		if body, err = req.GetBody(); err != nil {
+			return
+		}
+
+		req.Body = body
+	}
+
+	c.drainBody(req, res)
+
+	// Keep the credentials on the request, so that retries don't have to be challenged again
+	req.Auth.authorize(req.Request)
+
+	req.Metrics.BytesSent += requestSize(req.Request)
+
+	return HTTPClient.Do(req.Request)
+}
+
+// scheme returns the authentication scheme of the type, as found in WWW-Authenticate challenges.
+func (t AuthType) scheme() string {
+	switch t {
+	case DigestAuth:
+		return "digest"
+	case BasicAuth:
+		return "basic"
+	case BearerAuth:
+		return "bearer"
+	default:
+		return ""
+	}
+}
+
+// authorize sets the Authorization header of req from the credentials.
+func (a *Auth) authorize(req *http.Request) {
+	switch a.Type {
+	case BasicAuth:
+		req.SetBasicAuth(a.Username, a.Password)
+	case BearerAuth:
+		req.Header.Set(headers.Authorization, "Bearer "+a.Token)
+	}
+}
+
+// hasChallenge reports whether one of the WWW-Authenticate header values offers scheme. Several
+// challenges may share a header value, e.g. `Basic realm="a", Bearer realm="b"`: a comma separated
+// item starting with a token not followed by '=' starts a new challenge.
+func hasChallenge(values []string, scheme string) bool {
+	if scheme == "" {
+		return false
+	}
+
+	for _, value := range values {
+		for _, item := range splitChallengeItems(value) {
+			item = strings.TrimSpace(item)
+
+			token, _, _ := strings.Cut(item, " ")
+
+			if token == "" || strings.Contains(token, "=") {
+				continue
+			}
+
+			if strings.EqualFold(token, scheme) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// splitChallengeItems splits a WWW-Authenticate header value on commas outside quoted strings.
+func splitChallengeItems(value string) (items []string) {
+	quoted := false
+	escaped := false
+	start := 0
+
+	for i, r := range value {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && quoted:
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+
+	items = append(items, value[start:])
+
+	return
+}
@@ -7,6 +7,7 @@ package hqgohttp
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"io"
 	"math"
 	"net/http"
 	"sync"
@@ -16,6 +17,13 @@ import (
 // Backoff specifies a policy for how long to wait between retries.
 type Backoff func(min, max time.Duration, attemptNum int, resp *http.Response) time.Duration
 
+// LatencyBackoff is like Backoff, but is also given how long the failed attempt took,
+// so that fast failures (e.g. connection refused) and slow ones (e.g. timeouts) can be told apart.
+//
+// It is a separate type rather than an extension of Backoff so that existing Backoff
+// implementations keep working unchanged. When both are configured, LatencyBackoff wins.
+type LatencyBackoff func(min, max time.Duration, attemptNum int, resp *http.Response, elapsed time.Duration) time.Duration
+
 // DefaultBackoff provides a callback for client.Backoff
 // implements the standard exponential backoff without jitter.
 // i.e The delay between retries is doubled with each attempt, up to a maximum delay.
@@ -135,11 +143,72 @@ func ExponentialJitterBackoff() func(min, max time.Duration, attemptNum int, res
 	}
 }
 
-// Helper function to get a float64 value between 0 and 1 using crypto/rand
+// AdaptiveBackoff provides a callback for client.LatencyBackoff which
+// implements exponential backoff scaled by how long the failed attempt took.
+// A fast failure waits close to min, while a slow one waits up to the full
+// exponential delay. The scale is elapsed / (elapsed + min), so attempts much
+// faster than min get a short wait and attempts much slower than min get the
+// full delay, still capped to max.
+func AdaptiveBackoff() func(min, max time.Duration, attemptNum int, resp *http.Response, elapsed time.Duration) time.Duration {
+	return func(min, max time.Duration, attemptNum int, resp *http.Response, elapsed time.Duration) time.Duration {
+		if elapsed+min <= 0 {
+			return min
+		}
+
+		mult := math.Pow(2, float64(attemptNum)) * float64(min)
+		mult *= float64(elapsed) / float64(elapsed+min)
+
+		// Compare as float first, as the delay may not fit in a time.Duration.
+		if mult > float64(max) {
+			return max
+		}
+
+		sleep := time.Duration(mult)
+
+		if sleep < min {
+			sleep = min
+		}
+
+		return sleep
+	}
+}
+
+var (
+	// randReader is the source of randomness of the jitter backoffs
+	randReader io.Reader = rand.Reader
+	// randReaderMutex guards randReader, as sources such as math/rand aren't threadsafe
+	randReaderMutex = &sync.Mutex{}
+)
+
+// SetRandomSource replaces the source of randomness of the jitter backoffs, crypto/rand
+// by default. A seeded math/rand.Rand, for one, makes jitter deterministic in tests, or cheaper
+// under contention. Reads from the source are serialized. A nil reader restores crypto/rand.
+func SetRandomSource(reader io.Reader) {
+	randReaderMutex.Lock()
+	defer randReaderMutex.Unlock()
+
+	if reader == nil {
+		reader = rand.Reader
+	}
+
+	randReader = reader
+}
+
+// readRandom fills buf from the random source.
+func readRandom(buf []byte) (err error) {
+	randReaderMutex.Lock()
+	defer randReaderMutex.Unlock()
+
+	_, err = io.ReadFull(randReader, buf)
+
+	return
+}
+
+// Helper function to get a float64 value between 0 and 1 using the random source
 func cryptoRandFloat64() float64 {
 	var buf [8]byte
 
-	_, err := rand.Read(buf[:])
+	err := readRandom(buf[:])
 	if err != nil {
 		panic(err) // handle this error appropriately
 	}
@@ -148,7 +217,7 @@ func cryptoRandFloat64() float64 {
 	return float64(binary.LittleEndian.Uint64(buf[:])) / float64(1<<64)
 }
 
-// Helper function to get a random integer between 0 and max using crypto/rand
+// Helper function to get a random integer between 0 and max using the random source
 func cryptoRandInt(max int) int {
 	if max <= 0 {
 		return 0
@@ -160,7 +229,7 @@ func cryptoRandInt(max int) int {
 	buf := make([]byte, 8)
 
 	for {
-		_, err := rand.Read(buf)
+		err := readRandom(buf)
 		if err != nil {
 			panic(err) // handle this error appropriately
 		}
@@ -172,3 +241,33 @@ func cryptoRandInt(max int) int {
 		}
 	}
 }
+
+// BackoffScheduler batches the wake-ups of retries into waves, aligned to ticks of a fixed
+// period, rather than letting each request wake at its own time. Ticks are aligned to the Unix
+// epoch, so every client using a scheduler of the same period, across processes with
+// synchronized clocks, retries in the same waves.
+type BackoffScheduler struct {
+	tick time.Duration
+}
+
+// NewBackoffScheduler returns a scheduler of waves tick apart, 100ms if tick isn't positive.
+func NewBackoffScheduler(tick time.Duration) *BackoffScheduler {
+	if tick <= 0 {
+		tick = defaultBackoffSchedulerTick
+	}
+
+	return &BackoffScheduler{tick: tick}
+}
+
+// align rounds wait up so that the wait, started at now, ends on the next tick.
+func (s *BackoffScheduler) align(now time.Time, wait time.Duration) time.Duration {
+	wake := now.Add(wait)
+
+	if offset := time.Duration(wake.UnixNano() % int64(s.tick)); offset > 0 {
+		wait += s.tick - offset
+	}
+
+	return wait
+}
+
+const defaultBackoffSchedulerTick = 100 * time.Millisecond
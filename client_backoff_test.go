@@ -0,0 +1,96 @@
+package hqgohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestCapBackoff(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	client := &Client{clock: clock}
+
+	deadline := func(d time.Duration) context.Context {
+		ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(d))
+
+		t.Cleanup(cancel)
+
+		return ctx
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		wait time.Duration
+		want time.Duration
+		ok   bool
+	}{
+		{"no deadline", context.Background(), time.Minute, time.Minute, true},
+		{"distant deadline", deadline(time.Hour), time.Minute, time.Minute, true},
+		{"close deadline", deadline(time.Second), time.Minute, time.Second - minAttemptWindow, true},
+		{"no time left", deadline(minAttemptWindow), time.Minute, 0, false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := client.capBackoff(tt.ctx, tt.wait); got != tt.want || ok != tt.ok {
+			t.Errorf("%s: got %s, %v, want %s, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBackoffWithinDeadline(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&hits, 1)
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 5
+	options.RetryWaitMin = 10 * time.Second
+	options.RetryWaitMax = 10 * time.Second
+	options.CheckRetry = func(_ context.Context, res *http.Response, err error) (bool, error) {
+		return err != nil || res.StatusCode >= 500, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	req, err := NewRequestWithContext(ctx, methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	if _, err = client.Do(req); err == nil {
+		t.Fatal("got no error, want the request given up on")
+	}
+
+	// The 10s backoff is cut short to retry once before the deadline, then the retries stop
+	if got := atomic.LoadInt32(&hits); got != 2 {
+		t.Fatalf("got %d attempts, want 2", got)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("gave up after %s, want the deadline to bound the backoff", elapsed)
+	}
+}
@@ -0,0 +1,69 @@
+package hqgohttp
+
+// This file contains the pool of buffers reused to drain and snapshot response bodies,
+// sparing an allocation per request under high throughput.
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// getBuffer returns a buffer of Options.BufferSize bytes from the client's pool.
+func (c *Client) getBuffer() *[]byte {
+	buf, ok := c.buffers.Get().(*[]byte)
+	if !ok {
+		size := c.options.BufferSize
+
+		if size <= 0 {
+			size = defaultBufferSize
+		}
+
+		b := make([]byte, size)
+
+		buf = &b
+	}
+
+	return buf
+}
+
+// putBuffer returns a buffer to the client's pool.
+func (c *Client) putBuffer(buf *[]byte) {
+	c.buffers.Put(buf)
+}
+
+// discard reads r until io.EOF using a pooled buffer, returning the number of bytes read.
+func (c *Client) discard(r io.Reader) (n int64, err error) {
+	buf := c.getBuffer()
+	defer c.putBuffer(buf)
+
+	// Hide io.Discard's ReadFrom, which would bypass the pooled buffer
+	return io.CopyBuffer(struct{ io.Writer }{io.Discard}, r, *buf)
+}
+
+// snapshot reads up to limit bytes of r using a pooled buffer, returning a copy sized to the data read.
+func (c *Client) snapshot(r io.Reader, limit int64) (data []byte, err error) {
+	buf := c.getBuffer()
+	defer c.putBuffer(buf)
+
+	var snapshot bytes.Buffer
+
+	_, err = io.CopyBuffer(struct{ io.Writer }{&snapshot}, io.LimitReader(r, limit), *buf)
+
+	data = snapshot.Bytes()
+
+	return
+}
+
+// snapshotBody buffers up to RespReadLimit bytes of the response body, replacing it with
+// a re-readable one. The original body is closed.
+func (c *Client) snapshotBody(res *http.Response) {
+	body, _ := c.snapshot(res.Body, c.options.RespReadLimit)
+
+	res.Body.Close()
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+}
+
+const defaultBufferSize = 32 * 1024
@@ -0,0 +1,77 @@
+package hqgohttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestSnapshotBody(t *testing.T) {
+	t.Parallel()
+
+	options := *DefaultOptionsSingle
+	options.BufferSize = 4
+	options.RespReadLimit = 10
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := &http.Response{Body: io.NopCloser(strings.NewReader("0123456789abcdef"))}
+
+	client.snapshotBody(res)
+
+	// Reusing the pooled buffer doesn't alter the snapshot
+	if _, err := client.discard(strings.NewReader("zzzzzzzzzzzzzzzz")); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil || string(body) != "0123456789" || res.ContentLength != 10 {
+		t.Fatalf("got %q, %d, %v, want %q, 10", body, res.ContentLength, err, "0123456789")
+	}
+}
+
+func BenchmarkDrainBody(b *testing.B) {
+	client, err := New(DefaultOptionsSpraying)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, "http://example.com", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	payload := make([]byte, 16<<10)
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.SetBytes(int64(len(payload)))
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			res := &http.Response{Body: io.NopCloser(bytes.NewReader(payload))}
+
+			// A buffer allocated per call, as io.Copy does for writers without ReadFrom
+			if _, err := io.Copy(struct{ io.Writer }{io.Discard}, io.LimitReader(res.Body, client.options.RespReadLimit)); err != nil {
+				b.Fatal(err)
+			}
+
+			res.Body.Close()
+		}
+	})
+
+	b.Run("pooled", func(b *testing.B) {
+		b.SetBytes(int64(len(payload)))
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			client.drainBody(req, &http.Response{Body: io.NopCloser(bytes.NewReader(payload))})
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package hqgohttp
+
+// This file contains code for bucketing request outcomes into broad categories.
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// ResponseClass is the category of a request outcome.
+type ResponseClass uint8
+
+const (
+	// ClassUnknown is the class of a missing outcome (no response and no error).
+	ClassUnknown ResponseClass = iota
+	// ClassInformational is the class of 1xx responses.
+	ClassInformational
+	// ClassSuccess is the class of 2xx responses.
+	ClassSuccess
+	// ClassRedirect is the class of 3xx responses.
+	ClassRedirect
+	// ClassClientError is the class of 4xx responses.
+	ClassClientError
+	// ClassServerError is the class of 5xx responses.
+	ClassServerError
+	// ClassTimeout is the class of requests that failed because they timed out.
+	ClassTimeout
+	// ClassTransportError is the class of requests that failed without a response for other reasons.
+	ClassTransportError
+)
+
+func (c ResponseClass) String() string {
+	switch c {
+	case ClassInformational:
+		return "informational"
+	case ClassSuccess:
+		return "success"
+	case ClassRedirect:
+		return "redirect"
+	case ClassClientError:
+		return "client_error"
+	case ClassServerError:
+		return "server_error"
+	case ClassTimeout:
+		return "timeout"
+	case ClassTransportError:
+		return "transport_error"
+	case ClassUnknown:
+	}
+
+	return "unknown"
+}
+
+// ClassifyResponse returns the class of a request outcome, as returned by Do. Errors take
+// precedence over the response: timeouts are told apart from other transport errors.
+// Status codes outside of 100-599 are ClassUnknown.
+func ClassifyResponse(resp *http.Response, err error) ResponseClass {
+	if err != nil {
+		var netErr net.Error
+
+		if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return ClassTimeout
+		}
+
+		return ClassTransportError
+	}
+
+	if resp == nil {
+		return ClassUnknown
+	}
+
+	switch resp.StatusCode / 100 {
+	case 1:
+		return ClassInformational
+	case 2:
+		return ClassSuccess
+	case 3:
+		return ClassRedirect
+	case 4:
+		return ClassClientError
+	case 5:
+		return ClassServerError
+	}
+
+	return ClassUnknown
+}
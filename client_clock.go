@@ -0,0 +1,66 @@
+package hqgohttp
+
+// This file contains the clock abstraction used by the client for backoff waits and timeouts.
+// Swapping the real clock for a fake one allows retry behavior to be exercised deterministically.
+
+import "time"
+
+// Clock abstracts the passage of time for the client.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a new Timer that will send the current time on its channel after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer abstracts a single event timer created by a Clock.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing.
+	Stop() bool
+}
+
+// expired is a closed channel standing in for a timer that already fired.
+var expired = func() chan time.Time {
+	c := make(chan time.Time)
+
+	close(c)
+
+	return c
+}()
+
+// realClock is the Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{t: time.NewTimer(d)}
+}
+
+// realTimer is the Timer backed by a *time.Timer.
+type realTimer struct {
+	t *time.Timer
+}
+
+func (r *realTimer) C() <-chan time.Time {
+	return r.t.C
+}
+
+func (r *realTimer) Stop() bool {
+	return r.t.Stop()
+}
+
+// DefaultClock returns the Clock backed by the real wall clock.
+func DefaultClock() Clock {
+	return realClock{}
+}
@@ -0,0 +1,100 @@
+package hqgohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// fakeClock is a Clock whose waits advance its time at once, recording them.
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+
+	ch := make(chan time.Time, 1)
+
+	ch <- c.now
+
+	return ch
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	return realClock{}.NewTimer(d)
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestFakeClockBackoff(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	options := *DefaultOptionsSingle
+	options.Clock = clock
+	options.RetryMax = 4
+	options.RetryWaitMin = time.Second
+	options.RetryWaitMax = 5 * time.Second
+	options.Backoff = DefaultBackoff()
+	options.CheckRetry = func(_ context.Context, res *http.Response, err error) (bool, error) {
+		return err != nil || res.StatusCode >= 500, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	if _, err = client.Do(req); err == nil {
+		t.Fatal("got no error, want the retries exhausted")
+	}
+
+	// The waits double from RetryWaitMin up to RetryWaitMax, without sleeping
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second}
+
+	if !reflect.DeepEqual(clock.waits, want) {
+		t.Fatalf("got waits %v, want %v", clock.waits, want)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("took %s, want the waits skipped", elapsed)
+	}
+}
@@ -0,0 +1,120 @@
+package hqgohttp
+
+// This file contains code for capping the number of concurrent requests, and of requests
+// being retried, across a client, and the work of the helpers across clients.
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// acquireRequestSlot blocks until a request slot is free or ctx is done.
+func (c *Client) acquireRequestSlot(ctx context.Context) error {
+	if c.requestSlots == nil {
+		return nil
+	}
+
+	return c.requestSlots.Acquire(ctx, 1)
+}
+
+// releaseRequestSlot frees a request slot.
+func (c *Client) releaseRequestSlot() {
+	if c.requestSlots == nil {
+		return
+	}
+
+	c.requestSlots.Release(1)
+}
+
+// holdRequestSlot ties the request slot to the response body, releasing it when the body is closed.
+// Without a response, the slot is released right away.
+func (c *Client) holdRequestSlot(res *http.Response) {
+	if c.requestSlots == nil {
+		return
+	}
+
+	if res == nil || res.Body == nil {
+		c.releaseRequestSlot()
+
+		return
+	}
+
+	res.Body = &releasingReadCloser{
+		ReadCloser: res.Body,
+		release:    c.releaseRequestSlot,
+	}
+}
+
+// tryRetrySlot admits the request into the retry phase if a retry slot is free, when
+// SkipRetriesOverLimit is set. Otherwise, admission is left to waitRetrySlot.
+func (c *Client) tryRetrySlot() bool {
+	if c.retrySlots == nil || !c.options.SkipRetriesOverLimit {
+		return true
+	}
+
+	return c.retrySlots.TryAcquire(1)
+}
+
+// waitRetrySlot admits the request into the retry phase, blocking until a retry slot is
+// free or ctx is done, unless SkipRetriesOverLimit is set and tryRetrySlot admitted it.
+func (c *Client) waitRetrySlot(ctx context.Context) error {
+	if c.retrySlots == nil || c.options.SkipRetriesOverLimit {
+		return nil
+	}
+
+	return c.retrySlots.Acquire(ctx, 1)
+}
+
+// releaseRetrySlot frees a retry slot.
+func (c *Client) releaseRetrySlot() {
+	if c.retrySlots == nil {
+		return
+	}
+
+	c.retrySlots.Release(1)
+}
+
+// acquireGlobalSlot blocks until a slot of Options.GlobalConcurrencyLimiter is free or ctx is
+// done. The returned function frees the slot, and may be called more than once.
+func (c *Client) acquireGlobalSlot(ctx context.Context) (release func(), err error) {
+	limiter := c.options.GlobalConcurrencyLimiter
+
+	if limiter == nil {
+		return func() {}, nil
+	}
+
+	if err = limiter.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			limiter.Release(1)
+		})
+	}, nil
+}
+
+// ActiveRequests returns the number of calls to Do in progress, the ones of helpers included.
+func (c *Client) ActiveRequests() int {
+	return int(atomic.LoadInt64(&c.activeRequests))
+}
+
+// releasingReadCloser calls release once, the first time the body is closed.
+type releasingReadCloser struct {
+	io.ReadCloser
+	once    sync.Once
+	release func()
+}
+
+func (r *releasingReadCloser) Close() (err error) {
+	err = r.ReadCloser.Close()
+
+	r.once.Do(r.release)
+
+	return
+}
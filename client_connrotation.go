@@ -0,0 +1,129 @@
+package hqgohttp
+
+// This file contains the rotation of connections after a set number of requests.
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/headers"
+)
+
+// connRequests counts the requests sent over the connections of a client. Connections are
+// keyed by their underlying net.Conn, which is all httptrace exposes of them. Entries are removed
+// once their connection is rotated, and entries of connections left idle long enough to have
+// been closed by the transport are pruned as the map grows.
+type connRequests struct {
+	mutex    sync.Mutex
+	requests map[net.Conn]*connUse
+}
+
+type connUse struct {
+	requests int
+	lastUsed time.Time
+}
+
+// add counts a request sent over conn, reporting whether it is the limit-th one. The count of
+// new connections starts over, as closed connections' addresses may be reused.
+func (r *connRequests) add(conn net.Conn, reused bool, limit int, now time.Time) (rotate bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.requests == nil {
+		r.requests = map[net.Conn]*connUse{}
+	}
+
+	if len(r.requests) >= connRequestsPruneSize {
+		for key, use := range r.requests {
+			if now.Sub(use.lastUsed) > connRequestsMaxIdle {
+				delete(r.requests, key)
+			}
+		}
+	}
+
+	use, ok := r.requests[conn]
+
+	if !ok || !reused {
+		use = &connUse{}
+
+		r.requests[conn] = use
+	}
+
+	use.requests++
+	use.lastUsed = now
+
+	if use.requests < limit {
+		return false
+	}
+
+	delete(r.requests, conn)
+
+	return true
+}
+
+// hopRequest holds the request of the current hop of a request following redirects.
+type hopRequest struct {
+	req *http.Request
+	// closing is set when the hop was made to close its connection
+	closing bool
+}
+
+// rotateConns makes the request close its connection once done, with `Connection: close`, when
+// it is the Options.MaxRequestsPerConn-th request sent over it, so that the next request dials
+// a fresh one. The connection is known once obtained from the pool, which httptrace reports.
+// Redirect hops are tracked by the client's CheckRedirect: with a custom HTTPClient, the
+// connections of redirected requests may serve a few requests more.
+func (c *Client) rotateConns(req *Request) {
+	if c.options.MaxRequestsPerConn <= 0 {
+		return
+	}
+
+	hop := &hopRequest{}
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(connInfo httptrace.GotConnInfo) {
+			conn := connInfo.Conn
+
+			if TLSConn, ok := conn.(interface{ NetConn() net.Conn }); ok {
+				conn = TLSConn.NetConn()
+			}
+
+			// net/http forks the request before sending it, its Close field can't be set,
+			// but headers are shared. The transport and the server close the connection
+			// after the response to a request with "Connection: close".
+			switch rotate := c.connRequests.add(conn, connInfo.Reused, c.options.MaxRequestsPerConn, c.clock.Now()); {
+			case rotate:
+				hop.req.Header.Set(headers.Connection, "close")
+
+				hop.closing = true
+			case hop.closing:
+				// A retry or redirect over another connection
+				hop.req.Header.Del(headers.Connection)
+
+				hop.closing = false
+			}
+		},
+	}
+
+	ctx := context.WithValue(req.Context(), hopRequestKey{}, hop)
+
+	req.WithContext(httptrace.WithClientTrace(ctx, trace))
+
+	hop.req = req.Request
+}
+
+// trackHop records req as the current hop of the request it redirects, if rotating connections.
+func trackHop(req *http.Request) {
+	if hop, ok := req.Context().Value(hopRequestKey{}).(*hopRequest); ok {
+		hop.req = req
+	}
+}
+
+const (
+	connRequestsPruneSize = 1024
+	connRequestsMaxIdle   = 2 * defaultIdleConnTimeout
+)
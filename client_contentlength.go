@@ -0,0 +1,100 @@
+package hqgohttp
+
+// This file contains code for sizing resources without downloading them, coping with servers
+// whose HEAD responses don't tell the size.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// ContentLength returns the size of the resource at URL, as declared by the server, and whether
+// it declared one; size is -1 when it didn't. It sends a HEAD first, and falls back to a GET of
+// the first byte, with `Range: bytes=0-0`, when the HEAD response has no Content-Length or HEAD
+// isn't allowed (405 or 501): the size is then read from the Content-Range of a 206, or from the
+// Content-Length of a 200 from servers ignoring ranges, whose body is not read. Sizes declared
+// by HEAD are trusted, they may differ from the actual body for misbehaving servers. Error
+// statuses fail with an error.
+func (c *Client) ContentLength(URL string) (size int64, ok bool, err error) {
+	URL = c.withDefaultScheme(URL)
+
+	req, err := NewRequest(methods.Head, URL, nil)
+	if err != nil {
+		return -1, false, err
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return -1, false, err
+	}
+
+	res.Body.Close()
+
+	switch {
+	case res.StatusCode == status.MethodNotAllowed || res.StatusCode == status.NotImplemented:
+		// HEAD isn't allowed, fall back to GET
+	case res.StatusCode >= 400:
+		return -1, false, fmt.Errorf("HEAD %s: unexpected status code %d", req.URL.Redacted(), res.StatusCode)
+	case res.ContentLength >= 0:
+		return res.ContentLength, true, nil
+	}
+
+	return c.rangedContentLength(URL)
+}
+
+// rangedContentLength returns the size of the resource at URL from a GET of its first byte.
+func (c *Client) rangedContentLength(URL string) (size int64, ok bool, err error) {
+	req, err := NewRequest(methods.Get, URL, nil)
+	if err != nil {
+		return -1, false, err
+	}
+
+	req.Header.Set(headers.Range, "bytes=0-0")
+	// The size of compressed bodies isn't the size of the resource
+	req.Header.Set(headers.AcceptEncoding, "identity")
+
+	res, err := c.Do(req)
+	if err != nil {
+		return -1, false, err
+	}
+
+	// Closing without reading discards the connection, rather than downloading the body
+	res.Body.Close()
+
+	switch {
+	// Empty resources have no first byte, their 416 may tell their size, as in "bytes */0"
+	case res.StatusCode == status.PartialContent, res.StatusCode == status.RequestedRangeNotSatisfiable:
+		size, ok = parseContentRangeSize(res.Header.Get(headers.ContentRange))
+	case res.StatusCode >= 400:
+		return -1, false, fmt.Errorf("GET %s: unexpected status code %d", req.URL.Redacted(), res.StatusCode)
+	case res.ContentLength >= 0:
+		size, ok = res.ContentLength, true
+	}
+
+	if !ok {
+		size = -1
+	}
+
+	return
+}
+
+// parseContentRangeSize returns the complete length of a Content-Range, e.g. 1234 of
+// "bytes 0-0/1234". ok is false when it is unknown ("*") or the value is invalid.
+func parseContentRangeSize(contentRange string) (size int64, ok bool) {
+	_, length, found := strings.Cut(contentRange, "/")
+	if !found {
+		return -1, false
+	}
+
+	size, err := strconv.ParseInt(strings.TrimSpace(length), 10, 64)
+	if err != nil || size < 0 {
+		return -1, false
+	}
+
+	return size, true
+}
@@ -0,0 +1,41 @@
+package hqgohttp
+
+// This file contains the context keys and helpers overriding client settings per request.
+// Keys are unexported struct types, so they can't collide with keys set by other packages.
+
+import "context"
+
+type (
+	// retryMaxKey overrides Options.RetryMax for a request
+	retryMaxKey struct{}
+	// checkRetryKey overrides the client's retry policy for a request
+	checkRetryKey struct{}
+	// hopRequestKey holds the current hop of a request rotating connections
+	hopRequestKey struct{}
+	// httpClientKey routes a request through a specific *http.Client
+	httpClientKey struct{}
+	// tagsKey holds the observability tags attached to a request
+	tagsKey struct{}
+	// skipTLSVerifyKey skips the TLS certificate verification of a request
+	skipTLSVerifyKey struct{}
+	// expectedStatusKey holds the status code expected in answer to a request
+	expectedStatusKey struct{}
+	// respReadLimitKey holds the client's RespReadLimit for body-aware retry policies
+	respReadLimitKey struct{}
+	// valuesKey holds the state attached to a request with SetValue
+	valuesKey struct{}
+	// redirectStartKey holds the start of the redirect chain of a request attempt
+	redirectStartKey struct{}
+)
+
+// WithRetryMax returns a copy of ctx overriding the client's RetryMax for requests using it.
+func WithRetryMax(ctx context.Context, retryMax int) context.Context {
+	return context.WithValue(ctx, retryMaxKey{}, retryMax)
+}
+
+// WithCheckRetry returns a copy of ctx replacing the client's retry policy, CheckRetry and
+// CheckRetryFull alike, with policy for requests using it, e.g. to disable retries for one
+// call through a shared client. A nil policy keeps the client's.
+func WithCheckRetry(ctx context.Context, policy CheckRetry) context.Context {
+	return context.WithValue(ctx, checkRetryKey{}, policy)
+}
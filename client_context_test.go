@@ -0,0 +1,145 @@
+package hqgohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestContextOverrides(t *testing.T) {
+	t.Parallel()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 1
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+	options.CheckRetry = func(_ context.Context, res *http.Response, err error) (bool, error) {
+		return err != nil || res.StatusCode >= 500, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noRetry := func(context.Context, *http.Response, error) (bool, error) {
+		return false, nil
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want int32
+	}{
+		{"client settings", context.Background(), 2},
+		{"WithRetryMax", WithRetryMax(context.Background(), 3), 4},
+		{"deprecated key", context.WithValue(context.Background(), RetryMax, 2), 3},
+		{"WithRetryMax over deprecated key", WithRetryMax(context.WithValue(context.Background(), RetryMax, 2), 0), 1},
+		// A plain string key doesn't collide with the override keys
+		{"foreign key", context.WithValue(context.Background(), "retry-max", 5), 2}, //nolint:staticcheck // On purpose
+		{"WithCheckRetry", WithCheckRetry(WithRetryMax(context.Background(), 3), noRetry), 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var hits int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				atomic.AddInt32(&hits, 1)
+
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer server.Close()
+
+			req, err := NewRequestWithContext(tt.ctx, methods.Get, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res, err := client.Do(req); err == nil {
+				res.Body.Close()
+			}
+
+			if got := atomic.LoadInt32(&hits); got != tt.want {
+				t.Fatalf("got %d attempts, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithCheckRetry(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&hits, 1)
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 2
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+	options.CheckRetry = func(context.Context, *http.Response, error) (bool, error) {
+		return false, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int32
+
+	// Retries the 503s the client's policy gives up on
+	policy := func(_ context.Context, res *http.Response, err error) (bool, error) {
+		atomic.AddInt32(&calls, 1)
+
+		return err != nil || res.StatusCode == http.StatusServiceUnavailable, nil
+	}
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantHits  int32
+		wantCalls int32
+	}{
+		{"override", WithCheckRetry(context.Background(), policy), 3, 3},
+		{"unset", context.Background(), 1, 0},
+		{"nil override", WithCheckRetry(context.Background(), nil), 1, 0},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&hits, 0)
+		atomic.StoreInt32(&calls, 0)
+
+		req, err := NewRequestWithContext(tt.ctx, methods.Get, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if res, err := client.Do(req); err == nil {
+			res.Body.Close()
+		}
+
+		if got := atomic.LoadInt32(&hits); got != tt.wantHits {
+			t.Errorf("%s: got %d attempts, want %d", tt.name, got, tt.wantHits)
+		}
+
+		if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+			t.Errorf("%s: got %d calls of the policy, want %d", tt.name, got, tt.wantCalls)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package hqgohttp
+
+// This file contains the global deadline of clients, time boxing all their requests at once.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrGlobalDeadlineExceeded is returned by Do, without sending the request, once the global
+// deadline of the client, Options.GlobalDeadline or Options.MaxRuntime, has passed.
+var ErrGlobalDeadlineExceeded = errors.New("global deadline exceeded")
+
+// globalDeadline returns the earliest of Options.GlobalDeadline and of Options.MaxRuntime from
+// now, zero if neither is set.
+func globalDeadline(options *Options, now time.Time) (deadline time.Time) {
+	deadline = options.GlobalDeadline
+
+	if options.MaxRuntime > 0 {
+		if runtime := now.Add(options.MaxRuntime); deadline.IsZero() || runtime.Before(deadline) {
+			deadline = runtime
+		}
+	}
+
+	return
+}
+
+// applyGlobalDeadline fails requests past the global deadline of the client, and bounds the
+// context of the others by it. The bounded context is set on a shallow copy of the request,
+// so that the caller's request, which may be sent again, e.g. by a reconnecting stream, keeps
+// its own. The returned function, to call with the outcome of Do, puts the caller's request
+// back and releases the context once the response body, if any, is closed.
+func (c *Client) applyGlobalDeadline(req *Request) (done func(res *http.Response, err error), err error) {
+	done = func(*http.Response, error) {}
+
+	if c.deadline.IsZero() {
+		return
+	}
+
+	if !c.clock.Now().Before(c.deadline) {
+		return done, ErrGlobalDeadlineExceeded
+	}
+
+	original := req.Request
+
+	ctx, cancel := context.WithDeadline(original.Context(), c.deadline)
+
+	req.Request = original.WithContext(ctx)
+
+	done = func(res *http.Response, _ error) {
+		req.Request = original
+
+		// A response may come along with an error, e.g. with ReturnLastResponse, its body is
+		// still to be read
+		if res == nil || res.Body == nil {
+			cancel()
+
+			return
+		}
+
+		res.Body = &releasingReadCloser{ReadCloser: res.Body, release: cancel}
+	}
+
+	return
+}
@@ -0,0 +1,107 @@
+package hqgohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestGlobalDeadlineRejectsRequests(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.MaxRuntime = 50 * time.Millisecond
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request within the deadline failed: %v", err)
+	}
+
+	res.Body.Close()
+
+	time.Sleep(options.MaxRuntime)
+
+	if _, err = client.Get(server.URL); !errors.Is(err, ErrGlobalDeadlineExceeded) {
+		t.Fatalf("got %v, want ErrGlobalDeadlineExceeded", err)
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+
+	options.MaxRuntime = 0
+	options.GlobalDeadline = time.Now().Add(-time.Second)
+
+	if client, err = New(&options); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.Get(server.URL); !errors.Is(err, ErrGlobalDeadlineExceeded) {
+		t.Fatalf("got %v, want ErrGlobalDeadlineExceeded", err)
+	}
+}
+
+func TestGlobalDeadlineKeepsCallerContext(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("unavailable"))
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.MaxRuntime = time.Hour
+	options.RetryMax = 0
+	options.ReturnLastResponse = true
+	options.CheckRetry = func(ctx context.Context, res *http.Response, err error) (bool, error) {
+		return res != nil && res.StatusCode >= 500, err
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req)
+	if res == nil || err == nil {
+		t.Fatalf("got response %v and error %v, want both", res, err)
+	}
+
+	// The body of a response returned along with an error is still readable
+	body, readErr := ReadBodyString(res, 1024)
+	if readErr != nil || body != "unavailable" {
+		t.Fatalf("got body %q and error %v", body, readErr)
+	}
+
+	if _, ok := req.Context().Deadline(); ok {
+		t.Error("the caller's request context got the global deadline")
+	}
+
+	if req.Context().Err() != nil {
+		t.Errorf("the caller's request context is done: %v", req.Context().Err())
+	}
+}
@@ -0,0 +1,214 @@
+package hqgohttp
+
+// This file contains the decompression of response bodies, including the bodies with several
+// content encodings applied, which net/http leaves encoded.
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// ErrDecompressionBombDetected is returned when reading a body decoded by AutoDecompress whose
+// decoded size exceeds Limits.MaxResponseBodySize, or which expands beyond
+// Options.MaxCompressionRatio, e.g. a tiny gzip expanding to gigabytes.
+var ErrDecompressionBombDetected = errors.New("decompression bomb detected")
+
+// decompressBody decodes the response body according to its Content-Encoding list, when
+// Options.AutoDecompress is set. Encodings were applied in order, so they are decoded in
+// reverse. Decoding stops at the first unsupported encoding (e.g. br) or invalid stream: the
+// remaining encodings are left in the Content-Encoding header, and the request's
+// Metrics.DecodeIncomplete is set. Decoded encodings are removed from the header, along with
+// the Content-Length, which no longer applies. The decoded body is guarded against
+// decompression bombs, see bombGuard, and acceptGzip keeps net/http from decoding gzip bodies
+// past the guard.
+func (c *Client) decompressBody(req *Request, res *http.Response) {
+	if !c.options.AutoDecompress || res == nil || res.Body == nil || res.Uncompressed {
+		return
+	}
+
+	var encodings []string
+
+	for _, value := range res.Header.Values(headers.ContentEncoding) {
+		for _, encoding := range strings.Split(value, ",") {
+			if encoding = strings.ToLower(strings.TrimSpace(encoding)); encoding != "" {
+				encodings = append(encodings, encoding)
+			}
+		}
+	}
+
+	if len(encodings) == 0 {
+		return
+	}
+
+	var compressed int64
+
+	body := io.ReadCloser(&countingReadCloser{
+		ReadCloser: res.Body,
+		onRead: func(n int64) {
+			compressed += n
+		},
+	})
+
+	decoded := 0
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		reader, ok := newDecoder(encodings[i], body)
+
+		// Even when the stream can't be decoded, reader holds the bytes peeked at
+		body = &struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: reader,
+			Closer: body,
+		}
+
+		if !ok {
+			break
+		}
+
+		decoded++
+	}
+
+	res.Body = body
+
+	if decoded == 0 {
+		req.Metrics.DecodeIncomplete = true
+
+		return
+	}
+
+	if c.options.Limits.MaxResponseBodySize > 0 || c.options.MaxCompressionRatio > 0 {
+		res.Body = &bombGuard{
+			ReadCloser: body,
+			compressed: &compressed,
+			maxSize:    c.options.Limits.MaxResponseBodySize,
+			maxRatio:   c.options.MaxCompressionRatio,
+		}
+	}
+
+	res.ContentLength = -1
+
+	res.Header.Del(headers.ContentLength)
+	res.Header.Del(headers.ContentEncoding)
+
+	if remaining := encodings[:len(encodings)-decoded]; len(remaining) > 0 {
+		req.Metrics.DecodeIncomplete = true
+
+		res.Header.Set(headers.ContentEncoding, strings.Join(remaining, ", "))
+
+		return
+	}
+
+	res.Uncompressed = true
+}
+
+// acceptGzip asks for gzip responses itself, when AutoDecompress guards against decompression
+// bombs, so that net/http leaves them encoded for decompressBody to decode. net/http otherwise
+// asks for gzip on its own and decodes the response transparently, out of reach of the guard.
+// The request is left alone when net/http wouldn't do so: Accept-Encoding or Range is set, the
+// request is a HEAD, or the transport has compression disabled.
+func (c *Client) acceptGzip(req *Request) {
+	if !c.options.AutoDecompress || (c.options.Limits.MaxResponseBodySize <= 0 && c.options.MaxCompressionRatio <= 0) {
+		return
+	}
+
+	if req.Method == methods.Head || req.Header.Get(headers.AcceptEncoding) != "" || req.Header.Get(headers.Range) != "" {
+		return
+	}
+
+	if transport, ok := c.HTTPClient.Transport.(*http.Transport); ok && transport.DisableCompression {
+		return
+	}
+
+	req.Header.Set(headers.AcceptEncoding, "gzip")
+}
+
+// newDecoder returns a reader decoding r from encoding. ok is false for unsupported
+// encodings and streams that don't start as the encoding says: reader then reads the stream
+// as is, r itself or a buffered reader over it holding the bytes peeked at, so that nothing
+// is lost.
+func newDecoder(encoding string, r io.Reader) (reader io.Reader, ok bool) {
+	switch encoding {
+	case "identity":
+		return r, true
+	case "gzip", "x-gzip":
+		buffered := bufio.NewReader(r)
+
+		// Peek at the magic number, so that a mislabeled stream isn't consumed
+		if magic, err := buffered.Peek(2); err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+			return buffered, false
+		}
+
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return buffered, false
+		}
+
+		return gzipReader, true
+	case "deflate":
+		buffered := bufio.NewReader(r)
+
+		header, err := buffered.Peek(2)
+		if err != nil {
+			return buffered, false
+		}
+
+		// deflate is meant to be zlib wrapped, but some servers send raw deflate streams
+		if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			zlibReader, err := zlib.NewReader(buffered)
+			if err != nil {
+				return buffered, false
+			}
+
+			return zlibReader, true
+		}
+
+		return flate.NewReader(buffered), true
+	default:
+		return r, false
+	}
+}
+
+// bombGuard fails the reads of a decoded body with ErrDecompressionBombDetected once its
+// decoded size exceeds maxSize, or exceeds maxRatio times the compressed bytes read. The ratio
+// is only checked past bombGuardMinSize, as small bodies (e.g. runs of spaces) legitimately
+// compress well. Zero values disable the checks.
+type bombGuard struct {
+	io.ReadCloser
+	compressed   *int64
+	decompressed int64
+	maxSize      int64
+	maxRatio     float64
+}
+
+func (g *bombGuard) Read(p []byte) (n int, err error) {
+	n, err = g.ReadCloser.Read(p)
+
+	g.decompressed += int64(n)
+
+	if g.maxSize > 0 && g.decompressed > g.maxSize {
+		// Only hand out the bytes within the limit
+		n -= int(g.decompressed - g.maxSize)
+		g.decompressed = g.maxSize
+
+		return n, ErrDecompressionBombDetected
+	}
+
+	if g.maxRatio > 0 && g.decompressed > bombGuardMinSize && float64(g.decompressed) > g.maxRatio*float64(*g.compressed) {
+		return n, ErrDecompressionBombDetected
+	}
+
+	return
+}
+
+const bombGuardMinSize = 1024 * 1024
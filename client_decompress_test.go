@@ -0,0 +1,202 @@
+package hqgohttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	writer := gzip.NewWriter(buf)
+
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func zlibbed(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+
+	writer := zlib.NewWriter(buf)
+
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// getEncoded fetches body sent with the Content-Encoding encoding through an AutoDecompress
+// client. The request asks for the encodings itself, so that net/http leaves them encoded.
+func getEncoded(t *testing.T, options Options, encoding string, body []byte) (res *http.Response, req *Request) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+
+	options.AutoDecompress = true
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err = NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+
+	if res, err = client.Do(req); err != nil {
+		t.Fatal(err)
+	}
+
+	return
+}
+
+func TestDecompressNestedEncodings(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte(strings.Repeat("nested encodings ", 1000))
+
+	// gzip applied first, then deflate
+	res, req := getEncoded(t, *DefaultOptionsSingle, "gzip, deflate", zlibbed(t, gzipped(t, payload)))
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if !bytes.Equal(body, payload) {
+		t.Fatalf("got %d decoded bytes, want %d", len(body), len(payload))
+	}
+
+	if res.Header.Get("Content-Encoding") != "" || req.Metrics.DecodeIncomplete {
+		t.Errorf("Content-Encoding %q left, DecodeIncomplete %t", res.Header.Get("Content-Encoding"), req.Metrics.DecodeIncomplete)
+	}
+}
+
+func TestDecompressUnknownEncodingInChain(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte("left encoded")
+
+	// br applied first can't be decoded, the gzip layer on top of it is
+	res, req := getEncoded(t, *DefaultOptionsSingle, "br, gzip", gzipped(t, payload))
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if !bytes.Equal(body, payload) {
+		t.Errorf("got %q, want %q", body, payload)
+	}
+
+	if got := res.Header.Get("Content-Encoding"); got != "br" || !req.Metrics.DecodeIncomplete {
+		t.Errorf("got Content-Encoding %q and DecodeIncomplete %t, want br and true", got, req.Metrics.DecodeIncomplete)
+	}
+}
+
+func TestDecompressMislabeledEncoding(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte(strings.Repeat("plain text, not gzip ", 500))
+
+	res, req := getEncoded(t, *DefaultOptionsSingle, "gzip", payload)
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if !bytes.Equal(body, payload) {
+		t.Fatalf("got %d bytes, want the %d bytes sent", len(body), len(payload))
+	}
+
+	if !req.Metrics.DecodeIncomplete {
+		t.Error("DecodeIncomplete not set")
+	}
+}
+
+func TestDecompressionBomb(t *testing.T) {
+	t.Parallel()
+
+	// 16MB of zeros compress over a thousandfold
+	bomb := gzipped(t, make([]byte, 16<<20))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("got Accept-Encoding %q, want gzip", r.Header.Get("Accept-Encoding"))
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(bomb)
+	}))
+	defer server.Close()
+
+	limits := map[string]func(options *Options){
+		"ratio": func(options *Options) { options.MaxCompressionRatio = 100 },
+		"size":  func(options *Options) { options.Limits.MaxResponseBodySize = 4 << 20 },
+	}
+
+	for name, limit := range limits {
+		options := *DefaultOptionsSingle
+		options.AutoDecompress = true
+
+		limit(&options)
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The request doesn't ask for gzip, net/http would decode it transparently
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		n, err := io.Copy(io.Discard, res.Body)
+
+		res.Body.Close()
+
+		if !errors.Is(err, ErrDecompressionBombDetected) {
+			t.Errorf("%s: got %v after %d bytes, want ErrDecompressionBombDetected", name, err, n)
+		}
+	}
+}
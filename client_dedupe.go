@@ -0,0 +1,85 @@
+package hqgohttp
+
+// This file contains the deduplication of outgoing headers, which may pile up values when the
+// request's headers are merged with per-host and default headers, or set under several spellings.
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/hueristiq/hqgohttp/headers"
+)
+
+// deduplicateHeaders normalizes header in place: names spelled differently (e.g. "user-agent"
+// and "User-Agent") are merged under their canonical name, identical values of a header are
+// kept once, in order, and headers that may only be sent once (see singleValueHeaders) keep
+// their first value. Values under non-canonical names come first, in name order, as they can
+// only have been set on the request itself. With preserveCase, merged names keep the first
+// of these spellings rather than the canonical one.
+func deduplicateHeaders(header http.Header, preserveCase bool) {
+	spellings := make(map[string][]string, len(header))
+
+	for name := range header {
+		canonical := http.CanonicalHeaderKey(name)
+
+		spellings[canonical] = append(spellings[canonical], name)
+	}
+
+	for canonical, names := range spellings {
+		sort.Slice(names, func(i, j int) bool {
+			// The canonical spelling comes last: per-host and default headers are merged in
+			// under it, when the request doesn't have it, even if it has another spelling
+			if names[i] == canonical || names[j] == canonical {
+				return names[j] == canonical
+			}
+
+			return names[i] < names[j]
+		})
+
+		var values []string
+
+		seen := make(map[string]bool)
+
+		for _, name := range names {
+			for _, value := range header[name] {
+				if !seen[value] {
+					seen[value] = true
+
+					values = append(values, value)
+				}
+			}
+
+			delete(header, name)
+		}
+
+		if singleValueHeaders[canonical] && len(values) > 1 {
+			values = values[:1]
+		}
+
+		name := canonical
+
+		if preserveCase {
+			name = names[0]
+		}
+
+		header[name] = values
+	}
+}
+
+// singleValueHeaders lists the request headers that must not be sent more than once.
+var singleValueHeaders = map[string]bool{
+	headers.Authorization:      true,
+	headers.ContentLength:      true,
+	headers.ContentType:        true,
+	headers.From:               true,
+	headers.Host:               true,
+	headers.IfModifiedSince:    true,
+	headers.IfRange:            true,
+	headers.IfUnmodifiedSince:  true,
+	headers.MaxForwards:        true,
+	headers.Origin:             true,
+	headers.ProxyAuthorization: true,
+	headers.Range:              true,
+	headers.Referer:            true,
+	headers.UserAgent:          true,
+}
@@ -0,0 +1,86 @@
+package hqgohttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestDeduplicateHeaders(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(r.Header)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		dedup bool
+		want  http.Header
+	}{
+		{
+			"disabled", false,
+			http.Header{
+				"User-Agent": {"default", "explicit"},
+				"Accept":     {"application/json", "text/html", "text/html"},
+			},
+		},
+		{
+			"enabled", true,
+			http.Header{
+				"User-Agent": {"explicit"},
+				"Accept":     {"text/html", "application/json"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		options := *DefaultOptionsSingle
+		options.DeduplicateHeaders = tt.dedup
+		options.DefaultHeaders = http.Header{"User-Agent": {"default"}}
+		options.PerHostHeaders = map[string]http.Header{
+			"127.0.0.1": {"Accept": {"application/json"}},
+		}
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := NewRequest(methods.Get, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Set under non-canonical spellings, the merge of the per-host and default headers
+		// doesn't see them
+		req.Header["user-agent"] = []string{"explicit"}
+		req.Header["accept"] = []string{"text/html", "text/html"}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got http.Header
+
+		err = json.NewDecoder(res.Body).Decode(&got)
+
+		res.Body.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for name, want := range tt.want {
+			if !reflect.DeepEqual(got[name], want) {
+				t.Errorf("%s: got %s %q, want %q", tt.name, name, got[name], want)
+			}
+		}
+	}
+}
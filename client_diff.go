@@ -0,0 +1,201 @@
+package hqgohttp
+
+// This file contains code for comparing two responses, for change detection.
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hueristiq/hqgohttp/headers"
+)
+
+// DefaultDiffIgnoredHeaders lists the headers ignored by DiffResponses, changing on every
+// response regardless of the resource.
+var DefaultDiffIgnoredHeaders = []string{
+	headers.Age,
+	headers.Date,
+	headers.Expires,
+	headers.SetCookie,
+	headers.XRequestID,
+}
+
+// ResponseDiff is the difference between two responses, a and b.
+type ResponseDiff struct {
+	// StatusA and StatusB are the status codes of the responses.
+	StatusA, StatusB int
+	// HeadersAdded lists the headers of b missing from a.
+	HeadersAdded []string
+	// HeadersRemoved lists the headers of a missing from b.
+	HeadersRemoved []string
+	// HeadersChanged lists the headers of both with different values.
+	HeadersChanged []HeaderChange
+	// BodySizeA and BodySizeB are the sizes of the bodies read, up to the limit.
+	BodySizeA, BodySizeB int
+	// BodyFirstDiff is the offset of the first byte differing between the bodies, or -1 if
+	// they are the same. A body being the prefix of the other differs at the end of it.
+	BodyFirstDiff int
+	// BodyTruncated reports a body exceeding the limit, only its start being compared.
+	BodyTruncated bool
+}
+
+// HeaderChange is a header whose values differ between two responses.
+type HeaderChange struct {
+	// Name is the canonical name of the header.
+	Name string
+	// A and B are the values of the header in each response.
+	A, B []string
+}
+
+// StatusChanged reports whether the status codes differ.
+func (d *ResponseDiff) StatusChanged() bool {
+	return d.StatusA != d.StatusB
+}
+
+// BodyChanged reports whether the bodies differ.
+func (d *ResponseDiff) BodyChanged() bool {
+	return d.BodyFirstDiff >= 0
+}
+
+// Changed reports whether the responses differ in any way compared.
+func (d *ResponseDiff) Changed() bool {
+	return d.StatusChanged() || d.BodyChanged() ||
+		len(d.HeadersAdded) > 0 || len(d.HeadersRemoved) > 0 || len(d.HeadersChanged) > 0
+}
+
+// ResponseDiffer compares responses.
+type ResponseDiffer struct {
+	// IgnoredHeaders lists the headers left out of the comparison. Nil defaults to
+	// DefaultDiffIgnoredHeaders, an empty slice compares all of them.
+	IgnoredHeaders []string
+	// BodyLimit is the number of bytes of each body compared. Zero defaults to 1MB.
+	BodyLimit int64
+}
+
+// DiffResponses compares a and b with the default ResponseDiffer.
+func DiffResponses(a, b *http.Response) (*ResponseDiff, error) {
+	return (&ResponseDiffer{}).Diff(a, b)
+}
+
+// Diff compares the status codes, headers and bodies of a and b. Both bodies are read up to
+// the limit and closed, a body exceeding it isn't an error: only its start is compared.
+func (d *ResponseDiffer) Diff(a, b *http.Response) (diff *ResponseDiff, err error) {
+	limit := d.BodyLimit
+
+	if limit <= 0 {
+		limit = defaultDiffBodyLimit
+	}
+
+	bodyA, truncatedA, err := readDiffBody(a, limit)
+	if err != nil {
+		closeBody(b)
+
+		return
+	}
+
+	bodyB, truncatedB, err := readDiffBody(b, limit)
+	if err != nil {
+		return
+	}
+
+	diff = &ResponseDiff{
+		StatusA:       a.StatusCode,
+		StatusB:       b.StatusCode,
+		BodySizeA:     len(bodyA),
+		BodySizeB:     len(bodyB),
+		BodyFirstDiff: firstDiff(bodyA, bodyB),
+		BodyTruncated: truncatedA || truncatedB,
+	}
+
+	d.diffHeaders(diff, a.Header, b.Header)
+
+	return
+}
+
+// diffHeaders records the headers added, removed and changed from a to b, sorted by name.
+func (d *ResponseDiffer) diffHeaders(diff *ResponseDiff, a, b http.Header) {
+	ignored := d.IgnoredHeaders
+
+	if ignored == nil {
+		ignored = DefaultDiffIgnoredHeaders
+	}
+
+	skip := make(map[string]bool, len(ignored))
+
+	for _, name := range ignored {
+		skip[http.CanonicalHeaderKey(name)] = true
+	}
+
+	for name, valuesA := range a {
+		if skip[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+
+		valuesB, ok := b[name]
+
+		switch {
+		case !ok:
+			diff.HeadersRemoved = append(diff.HeadersRemoved, name)
+		case strings.Join(valuesA, "\n") != strings.Join(valuesB, "\n"):
+			diff.HeadersChanged = append(diff.HeadersChanged, HeaderChange{Name: name, A: valuesA, B: valuesB})
+		}
+	}
+
+	for name := range b {
+		if _, ok := a[name]; !ok && !skip[http.CanonicalHeaderKey(name)] {
+			diff.HeadersAdded = append(diff.HeadersAdded, name)
+		}
+	}
+
+	sort.Strings(diff.HeadersAdded)
+	sort.Strings(diff.HeadersRemoved)
+	sort.Slice(diff.HeadersChanged, func(i, j int) bool {
+		return diff.HeadersChanged[i].Name < diff.HeadersChanged[j].Name
+	})
+}
+
+// readDiffBody reads up to limit bytes of the body of res, reporting whether there was more.
+func readDiffBody(res *http.Response, limit int64) (body []byte, truncated bool, err error) {
+	if res.Body == nil {
+		return
+	}
+
+	body, err = ReadBodyBytes(res, limit)
+	if errors.Is(err, ErrBodyLimitExceeded) {
+		truncated, err = true, nil
+	}
+
+	return
+}
+
+// closeBody closes the body of res, if any.
+func closeBody(res *http.Response) {
+	if res.Body != nil {
+		res.Body.Close()
+	}
+}
+
+// firstDiff returns the offset of the first byte differing between a and b, or -1.
+func firstDiff(a, b []byte) int {
+	if bytes.Equal(a, b) {
+		return -1
+	}
+
+	n := len(a)
+
+	if len(b) < n {
+		n = len(b)
+	}
+
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+
+	return n
+}
+
+const defaultDiffBodyLimit = 1 << 20
@@ -0,0 +1,72 @@
+package hqgohttp
+
+// This file contains the downgrade to HTTP/1.1 of requests failing on HTTP/2 specific errors.
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+)
+
+// isHTTP2DowngradeError reports whether err is an HTTP/2 error worth retrying over HTTP/1.1:
+// a GOAWAY, or a stream refused or reset with ENHANCE_YOUR_CALM. net/http bundles its own,
+// unexported, copy of the HTTP/2 errors, which are matched by their messages.
+func isHTTP2DowngradeError(err error) bool {
+	var goAwayErr http2.GoAwayError
+
+	if errors.As(err, &goAwayErr) {
+		return true
+	}
+
+	var streamErr http2.StreamError
+
+	if errors.As(err, &streamErr) {
+		return streamErr.Code == http2.ErrCodeRefusedStream || streamErr.Code == http2.ErrCodeEnhanceYourCalm
+	}
+
+	message := err.Error()
+
+	// e.g. "http2: server sent GOAWAY and closed the connection" or "http2: Transport received
+	// GOAWAY from server ErrCode:ENHANCE_YOUR_CALM"
+	return (strings.Contains(message, "http2: ") && strings.Contains(message, "GOAWAY")) ||
+		(strings.Contains(message, "stream error:") &&
+			(strings.Contains(message, http2.ErrCodeRefusedStream.String()) || strings.Contains(message, http2.ErrCodeEnhanceYourCalm.String())))
+}
+
+// useHTTP2Downgrade reports whether requests failing over HTTP/2 may be retried over HTTP/1.1.
+func (c *Client) useHTTP2Downgrade() bool {
+	return !c.options.ForceHTTP2 && !c.options.ForceHTTP10
+}
+
+// http11Client returns a client sharing the configuration of the client's transport, restricted
+// to HTTP/1.1. It is built on first use, and is nil for transports other than *http.Transport.
+func (c *Client) http11Client() *http.Client {
+	c.http11Once.Do(func() {
+		transport, ok := c.HTTPClient.Transport.(*http.Transport)
+		if !ok {
+			return
+		}
+
+		transport = transport.Clone()
+
+		// A non-nil empty map disables HTTP/2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		transport.ForceAttemptHTTP2 = false
+
+		if transport.TLSClientConfig != nil {
+			transport.TLSClientConfig.NextProtos = nil
+		}
+
+		c.http11 = &http.Client{
+			Transport:     transport,
+			CheckRedirect: c.HTTPClient.CheckRedirect,
+			Jar:           c.HTTPClient.Jar,
+			Timeout:       c.HTTPClient.Timeout,
+		}
+	})
+
+	return c.http11
+}
@@ -0,0 +1,89 @@
+package hqgohttp
+
+import (
+	"crypto/tls"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// goAway answers HTTP/2 requests with a GOAWAY frame and closes the connection, as flaky
+// servers do. The GOAWAY covers the request stream: transports silently retry the streams
+// it doesn't cover elsewhere.
+func goAway(_ *http.Server, conn *tls.Conn, _ http.Handler) {
+	defer conn.Close()
+
+	preface := make([]byte, len(http2.ClientPreface))
+
+	if _, err := io.ReadFull(conn, preface); err != nil {
+		return
+	}
+
+	framer := http2.NewFramer(conn, conn)
+
+	if err := framer.WriteSettings(); err != nil {
+		return
+	}
+
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			return
+		}
+
+		if headers, ok := frame.(*http2.HeadersFrame); ok {
+			framer.WriteGoAway(headers.StreamID, http2.ErrCodeEnhanceYourCalm, nil)
+
+			return
+		}
+	}
+}
+
+func TestHTTP2DowngradeOnGoAway(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	server.Config.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){"h2": goAway}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 0
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req.SkipTLSVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(body) != "HTTP/1.1" || req.Metrics.HTTP2Downgrades != 1 {
+		t.Fatalf("got a %s response after %d downgrades, want HTTP/1.1 after 1", body, req.Metrics.HTTP2Downgrades)
+	}
+}
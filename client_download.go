@@ -0,0 +1,164 @@
+package hqgohttp
+
+// This file contains code for streaming large responses to a writer, with progress reporting and
+// resumption of interrupted transfers through range requests.
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// Download executes the request and copies the response body to dst, returning the number of
+// bytes written. progress, if not nil, is called after every write with the bytes written so
+// far and the total from Content-Length, -1 when unknown (e.g. chunked).
+//
+// The response must be 200 OK or 206 Partial Content, otherwise an *UnexpectedStatusError is
+// returned. Retries apply until the response headers are received. Past that, a transfer of
+// a GET request interrupted mid-body is resumed, up to RetryMax times, with a range request
+// for the missing bytes, provided the server advertises `Accept-Ranges: bytes`. The resumed
+// request carries If-Range, so that a resource modified in between isn't stitched together.
+// A server answering with the whole body, or with another range than the missing one, has the
+// transfer restarted from the first byte, provided dst is an io.Seeker, e.g. an *os.File, which
+// is then truncated if it can be. Other interrupted transfers return the copy error, dst then
+// holds a partial body.
+func (c *Client) Download(req *Request, dst io.Writer, progress func(written, total int64)) (written int64, err error) {
+	res, err := c.DoExpect(req, status.OK, status.PartialContent)
+	if err != nil {
+		return
+	}
+
+	total := res.ContentLength
+
+	resumable := req.Method == methods.Get &&
+		res.StatusCode == status.OK &&
+		strings.EqualFold(res.Header.Get(headers.AcceptRanges), "bytes")
+
+	validator := downloadValidator(res)
+
+	writer := &progressWriter{
+		Writer:   dst,
+		total:    total,
+		progress: progress,
+	}
+
+	for resumes := 0; ; resumes++ {
+		var n int64
+
+		n, err = c.copyBody(writer, res.Body)
+
+		res.Body.Close()
+
+		written += n
+
+		if err == nil || !resumable || resumes >= c.getRetryMax(req) || req.Context().Err() != nil {
+			return
+		}
+
+		copyErr := err
+
+		rangeReq := req.Clone(req.Context())
+
+		rangeReq.Header.Set(headers.Range, fmt.Sprintf("bytes=%d-", written))
+
+		if validator != "" {
+			rangeReq.Header.Set(headers.IfRange, validator)
+		}
+
+		if res, err = c.Do(rangeReq); err != nil {
+			return written, copyErr
+		}
+
+		if res.StatusCode == status.PartialContent && contentRangeStart(res.Header.Get(headers.ContentRange)) == written {
+			continue
+		}
+
+		// The server sent another range than the missing one: download the whole body again
+		if res.StatusCode == status.PartialContent {
+			res.Body.Close()
+
+			if res, err = c.Do(req.Clone(req.Context())); err != nil {
+				return written, copyErr
+			}
+		}
+
+		if res.StatusCode != status.OK || !rewindDownload(dst) {
+			res.Body.Close()
+
+			return written, copyErr
+		}
+
+		written, writer.written = 0, 0
+
+		writer.total = res.ContentLength
+
+		validator = downloadValidator(res)
+	}
+}
+
+// downloadValidator returns the validator of the resource of res for If-Range: its strong
+// ETag, or else its Last-Modified date.
+func downloadValidator(res *http.Response) (validator string) {
+	validator = res.Header.Get(headers.ETag)
+
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = res.Header.Get(headers.LastModified)
+	}
+
+	return
+}
+
+// rewindDownload rewinds dst to write a body again from its start, truncating it if it can,
+// reporting whether it could.
+func rewindDownload(dst io.Writer) bool {
+	seeker, ok := dst.(io.Seeker)
+	if !ok {
+		return false
+	}
+
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return false
+	}
+
+	if truncater, ok := dst.(interface{ Truncate(size int64) error }); ok {
+		if err := truncater.Truncate(0); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// copyBody copies src to dst with a pooled buffer.
+func (c *Client) copyBody(dst io.Writer, src io.Reader) (n int64, err error) {
+	buf := c.getBuffer()
+	defer c.putBuffer(buf)
+
+	// Hide ReadFrom and WriteTo, which would bypass the pooled buffer and the progress reports
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
+
+// progressWriter reports the bytes written so far after every write.
+type progressWriter struct {
+	io.Writer
+	written  int64
+	total    int64
+	progress func(written, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (n int, err error) {
+	n, err = w.Writer.Write(p)
+
+	w.written += int64(n)
+
+	if w.progress != nil {
+		w.progress(w.written, w.total)
+	}
+
+	return
+}
@@ -0,0 +1,119 @@
+package hqgohttp
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// newWrongRangeServer serves a body whose first transfer is cut halfway, answering range
+// requests with the body from its start whatever the range asked for.
+func newWrongRangeServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+
+	var transfers int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Accept-Ranges", "bytes")
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Header.Get("Range") != "" {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(body)-1, len(body)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(body))
+
+			return
+		}
+
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+
+		if atomic.AddInt32(&transfers, 1) == 1 {
+			w.Write([]byte(body[:len(body)/2]))
+			w.(http.Flusher).Flush()
+
+			panic(http.ErrAbortHandler)
+		}
+
+		w.Write([]byte(body))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestDownloadWrongRange(t *testing.T) {
+	t.Parallel()
+
+	body := strings.Repeat("0123456789", 10000)
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 3
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("seekable", func(t *testing.T) {
+		t.Parallel()
+
+		server := newWrongRangeServer(t, body)
+
+		file, err := os.Create(filepath.Join(t.TempDir(), "download"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer file.Close()
+
+		req, err := NewRequest(methods.Get, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		written, err := client.Download(req, file, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := os.ReadFile(file.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if written != int64(len(body)) || string(got) != body {
+			t.Fatalf("wrote %d bytes, got a %d bytes file, want the %d bytes body restarted", written, len(got), len(body))
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		t.Parallel()
+
+		server := newWrongRangeServer(t, body)
+
+		req, err := NewRequest(methods.Get, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := &bytes.Buffer{}
+
+		// The bytes written can't be taken back, the range isn't stitched to them
+		if _, err := client.Download(req, buf, nil); err == nil {
+			t.Fatal("got no error, want the transfer interrupted")
+		}
+
+		if !strings.HasPrefix(body, buf.String()) {
+			t.Fatal("got a corrupted body, want a partial one")
+		}
+	})
+}
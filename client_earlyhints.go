@@ -0,0 +1,31 @@
+package hqgohttp
+
+// This file contains the reporting of 103 Early Hints responses.
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// traceEarlyHints calls Options.OnEarlyHints with the headers of every 103 Early Hints response
+// received for the request, redirect hops and retries included.
+func (c *Client) traceEarlyHints(req *Request) {
+	if c.options.OnEarlyHints == nil {
+		return
+	}
+
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == status.EarlyHints {
+				c.options.OnEarlyHints(http.Header(header).Clone())
+			}
+
+			return nil
+		},
+	}
+
+	req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
@@ -0,0 +1,74 @@
+package hqgohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestOnEarlyHints(t *testing.T) {
+	t.Parallel()
+
+	links := []string{"</style.css>; rel=preload; as=style", "</script.js>; rel=preload; as=script"}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		for _, link := range links {
+			w.Header().Add("Link", link)
+		}
+
+		w.WriteHeader(http.StatusEarlyHints)
+
+		w.Header().Del("Link")
+
+		w.Write([]byte("final"))
+	}))
+	defer server.Close()
+
+	mutex := &sync.Mutex{}
+
+	var hints []http.Header
+
+	options := *DefaultOptionsSingle
+	options.OnEarlyHints = func(header http.Header) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		hints = append(hints, header)
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", res.StatusCode, http.StatusOK)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(hints) != 1 {
+		t.Fatalf("got %d early hints, want 1", len(hints))
+	}
+
+	if got := hints[0].Values("Link"); !reflect.DeepEqual(got, links) {
+		t.Fatalf("got Link %q, want %q", got, links)
+	}
+}
@@ -0,0 +1,129 @@
+package hqgohttp
+
+// This file contains the registry of the encoders turning values into request bodies by content type.
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// ErrNoBodyEncoder is returned by PostAs and PutAs when no encoder is registered for the
+// content type.
+var ErrNoBodyEncoder = errors.New("no body encoder registered")
+
+// BodyEncoder encodes v into a request body.
+type BodyEncoder func(v interface{}) (io.Reader, error)
+
+var (
+	bodyEncoders = map[string]BodyEncoder{
+		"application/json": encodeJSON,
+		"application/xml":  encodeXML,
+		"text/xml":         encodeXML,
+	}
+	bodyEncodersMutex = &sync.RWMutex{}
+)
+
+// RegisterBodyEncoder registers enc as the encoder of the bodies of contentType, replacing
+// any previous one, the built-in JSON and XML encoders included. Content types are matched
+// by media type, case-insensitively and regardless of parameters: an encoder registered for
+// application/json also encodes `application/json; charset=utf-8` bodies. A nil enc
+// unregisters the encoder. It is safe to call concurrently with requests.
+func RegisterBodyEncoder(contentType string, enc func(v interface{}) (io.Reader, error)) {
+	mediaType := bodyMediaType(contentType)
+
+	bodyEncodersMutex.Lock()
+	defer bodyEncodersMutex.Unlock()
+
+	if enc == nil {
+		delete(bodyEncoders, mediaType)
+
+		return
+	}
+
+	bodyEncoders[mediaType] = enc
+}
+
+// EncodeBody encodes v with the encoder registered for contentType.
+func EncodeBody(contentType string, v interface{}) (body io.Reader, err error) {
+	bodyEncodersMutex.RLock()
+	enc, ok := bodyEncoders[bodyMediaType(contentType)]
+	bodyEncodersMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w for %q", ErrNoBodyEncoder, contentType)
+	}
+
+	if body, err = enc(v); err != nil {
+		return nil, fmt.Errorf("cannot encode %s body: %w", contentType, err)
+	}
+
+	return
+}
+
+// PostAs is a convenience method for doing POST requests with a body encoded from v by the
+// encoder registered for contentType, sent as the Content-Type.
+func (c *Client) PostAs(URL, contentType string, v interface{}) (*http.Response, error) {
+	return c.sendAs(methods.Post, URL, contentType, v)
+}
+
+// PutAs is a convenience method for doing PUT requests with a body encoded from v by the
+// encoder registered for contentType, sent as the Content-Type.
+func (c *Client) PutAs(URL, contentType string, v interface{}) (*http.Response, error) {
+	return c.sendAs(methods.Put, URL, contentType, v)
+}
+
+func (c *Client) sendAs(method, URL, contentType string, v interface{}) (*http.Response, error) {
+	body, err := EncodeBody(contentType, v)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := NewRequest(method, c.withDefaultScheme(URL), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set(headers.ContentType, contentType)
+
+	return c.Do(req)
+}
+
+// bodyMediaType returns the lowercase media type of contentType, without its parameters.
+func bodyMediaType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+func encodeJSON(v interface{}) (io.Reader, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(body), nil
+}
+
+func encodeXML(v interface{}) (io.Reader, error) {
+	body, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(body), nil
+}
@@ -0,0 +1,88 @@
+package hqgohttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegisterBodyEncoder(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Write([]byte(r.Method + " " + r.Header.Get("Content-Type") + " " + string(body)))
+	}))
+	defer server.Close()
+
+	client, err := New(DefaultOptionsSingle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errUnsupported := errors.New("unsupported value")
+
+	// The registry is global: the content type is unique to this test
+	const contentType = "application/x-hqgohttp-upper"
+
+	RegisterBodyEncoder(contentType, func(v interface{}) (io.Reader, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errUnsupported
+		}
+
+		return strings.NewReader(strings.ToUpper(s)), nil
+	})
+
+	send := func(send func(URL, contentType string, v interface{}) (*http.Response, error), contentType string, v interface{}) (string, error) {
+		res, err := send(server.URL, contentType, v)
+		if err != nil {
+			return "", err
+		}
+
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+
+		return string(body), err
+	}
+
+	tests := []struct {
+		name        string
+		send        func(URL, contentType string, v interface{}) (*http.Response, error)
+		contentType string
+		v           interface{}
+		want        string
+	}{
+		{"PostAs", client.PostAs, contentType, "hello", "POST " + contentType + " HELLO"},
+		{"PutAs", client.PutAs, contentType, "hello", "PUT " + contentType + " HELLO"},
+		// Matched by media type, case-insensitively and regardless of parameters
+		{"parameters", client.PostAs, "Application/X-Hqgohttp-Upper; charset=utf-8", "hello", "POST Application/X-Hqgohttp-Upper; charset=utf-8 HELLO"},
+		{"built-in JSON", client.PostAs, "application/json", map[string]int{"a": 1}, `POST application/json {"a":1}`},
+		{"built-in XML", client.PutAs, "text/xml", struct {
+			XMLName struct{} `xml:"a"`
+			B       int      `xml:"b"`
+		}{B: 1}, "PUT text/xml <a><b>1</b></a>"},
+	}
+
+	for _, tt := range tests {
+		got, err := send(tt.send, tt.contentType, tt.v)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := send(client.PostAs, contentType, 1); !errors.Is(err, errUnsupported) {
+		t.Errorf("got %v, want the error of the encoder", err)
+	}
+
+	RegisterBodyEncoder(contentType, nil)
+
+	if _, err := send(client.PostAs, contentType, "hello"); !errors.Is(err, ErrNoBodyEncoder) {
+		t.Errorf("got %v after unregistering, want ErrNoBodyEncoder", err)
+	}
+}
@@ -0,0 +1,56 @@
+package hqgohttp
+
+// This file contains code for validating response status codes against an expected set.
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// UnexpectedStatusError is returned by DoExpect when the response status isn't one of the allowed ones.
+type UnexpectedStatusError struct {
+	// Got is the status code of the response.
+	Got int
+	// Allowed lists the expected status codes.
+	Allowed []int
+	// Body is a snapshot of the start of the response body, truncated to RespReadLimit bytes.
+	Body []byte
+}
+
+func (e *UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d, expected one of %v", e.Got, e.Allowed)
+}
+
+// DoExpect executes the request and checks the response status code is one of allowed.
+// If it isn't, the response body is closed and an *UnexpectedStatusError holding a
+// snapshot of the body is returned. Otherwise the response is returned untouched.
+func (c *Client) DoExpect(req *Request, allowed ...int) (res *http.Response, err error) {
+	res, err = c.Do(req)
+	if err != nil {
+		return
+	}
+
+	for _, code := range allowed {
+		if res.StatusCode == code {
+			return
+		}
+	}
+
+	defer res.Body.Close()
+
+	limit := c.options.RespReadLimit
+
+	if limit <= 0 {
+		limit = defaultSnapshotLimit
+	}
+
+	body, _ := c.snapshot(res.Body, limit)
+
+	return nil, &UnexpectedStatusError{
+		Got:     res.StatusCode,
+		Allowed: allowed,
+		Body:    body,
+	}
+}
+
+const defaultSnapshotLimit = 4096
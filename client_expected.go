@@ -0,0 +1,48 @@
+package hqgohttp
+
+// This file contains predicates for errors that are meaningful outcomes rather than failures.
+// See Options.SuccessErrors.
+
+import (
+	"errors"
+	"net"
+	"syscall"
+)
+
+// IsConnectionRefused reports whether err is due to the remote host refusing the connection (closed port).
+func IsConnectionRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IsConnectionReset reports whether err is due to the remote host resetting the connection.
+func IsConnectionReset(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET)
+}
+
+// IsHostUnreachable reports whether err is due to the remote host or its network being unreachable.
+func IsHostUnreachable(err error) bool {
+	return errors.Is(err, syscall.EHOSTUNREACH) || errors.Is(err, syscall.ENETUNREACH)
+}
+
+// IsHostNotFound reports whether err is due to the host not resolving.
+func IsHostNotFound(err error) bool {
+	return errors.Is(err, ErrHostNotFound) || isHostNotFoundError(err)
+}
+
+// IsTimeout reports whether err is due to a timeout.
+func IsTimeout(err error) bool {
+	var netErr net.Error
+
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isExpectedError reports whether err matches one of the client's success errors.
+func (c *Client) isExpectedError(err error) bool {
+	for _, expected := range c.options.SuccessErrors {
+		if expected(err) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,158 @@
+package hqgohttp
+
+// This file contains the failover of requests across equivalent endpoints.
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrNoBaseURLs is returned by DoWithFailover when Options.BaseURLs is empty.
+var ErrNoBaseURLs = errors.New("no base URLs to fail over across")
+
+// failover tracks the health of the base URLs of the client. The preferred one is tried first,
+// and is demoted after failoverThreshold failures in a row.
+type failover struct {
+	mutex     sync.Mutex
+	endpoints []string
+	failures  []int
+	preferred int
+}
+
+func newFailover(endpoints []string) *failover {
+	return &failover{
+		endpoints: endpoints,
+		failures:  make([]int, len(endpoints)),
+	}
+}
+
+// order returns the indexes of the endpoints in the order to try them: the preferred one, then
+// the ones following it.
+func (f *failover) order() (indexes []int) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for i := range f.endpoints {
+		indexes = append(indexes, (f.preferred+i)%len(f.endpoints))
+	}
+
+	return
+}
+
+// record records the outcome of a request to the endpoint i, demoting it in favor of the next
+// one if it is the preferred one and keeps failing.
+func (f *failover) record(i int, ok bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if ok {
+		f.failures[i] = 0
+
+		return
+	}
+
+	f.failures[i]++
+
+	if i == f.preferred && f.failures[i] >= failoverThreshold {
+		f.failures[i] = 0
+		f.preferred = (i + 1) % len(f.endpoints)
+	}
+}
+
+// DoWithFailover sends a request for path, e.g. "/v1/items?page=2", to the endpoints of
+// Options.BaseURLs, joined to each of them in turn. A connection-level failure or a 5xx response
+// fails over to the next endpoint right away, without retrying; only the last endpoint left is
+// retried, consuming the retry budget, and its response is returned even if it is a 5xx. body is
+// buffered so that it can be sent again.
+//
+// Endpoints are tried starting from the preferred one, the first base URL at first. After 3
+// failures in a row, the preferred endpoint is demoted in favor of the next one, which requests
+// then start from, until it fails in turn.
+//
+// The metrics returned are the ones of the request to the endpoint that answered, whose base
+// URL is in Metrics.EndpointUsed.
+func (c *Client) DoWithFailover(ctx context.Context, method, path string, body interface{}) (res *http.Response, metrics Metrics, err error) {
+	if c.failover == nil {
+		return nil, metrics, ErrNoBaseURLs
+	}
+
+	if body != nil {
+		var reader io.Reader
+
+		if reader, _, err = getReusableBodyandContentLength(body); err != nil {
+			return
+		}
+
+		if body, err = io.ReadAll(reader); err != nil {
+			return
+		}
+	}
+
+	indexes := c.failover.order()
+
+	for n, i := range indexes {
+		endpoint := c.failover.endpoints[i]
+
+		last := n == len(indexes)-1
+
+		attemptCtx := ctx
+
+		if !last {
+			attemptCtx = WithRetryMax(ctx, 0)
+		}
+
+		var req *Request
+
+		if req, err = NewRequestWithContext(attemptCtx, method, joinBaseURL(endpoint, path), body); err != nil {
+			return
+		}
+
+		res, err = c.Do(req)
+
+		metrics = req.Metrics
+		metrics.EndpointUsed = endpoint
+
+		failed := err != nil || res.StatusCode >= 500
+
+		// The caller giving up says nothing about the health of the endpoint
+		if ctx.Err() != nil {
+			if res != nil {
+				res.Body.Close()
+			}
+
+			return nil, metrics, ctx.Err()
+		}
+
+		c.failover.record(i, !failed)
+
+		if !failed || last {
+			return
+		}
+
+		if res != nil {
+			c.drainBody(req, res)
+		}
+	}
+
+	return
+}
+
+// joinBaseURL joins path to baseURL, with a single slash between the two.
+func joinBaseURL(baseURL, path string) string {
+	if path == "" {
+		return baseURL
+	}
+
+	if strings.HasPrefix(path, "?") {
+		return baseURL + path
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// failoverThreshold is the number of failures in a row demoting the preferred endpoint.
+const failoverThreshold = 3
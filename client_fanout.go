@@ -0,0 +1,94 @@
+package hqgohttp
+
+// This file contains code for sending one payload to many URLs.
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// BatchResult holds the outcome of a single request of a batch.
+// If Response is not nil, it is up to the caller to close its body.
+type BatchResult struct {
+	URL      string
+	Response *http.Response
+	Err      error
+}
+
+// Fanout POSTs body, of type bodyType, to every URL, at most concurrency at a time (all at
+// once if concurrency isn't positive). The payload is shared by all the requests, and being
+// in memory, it is replayed as is on retries. The results are in the order of URLs. Once
+// ctx is done, the URLs not requested yet fail with the context error.
+func (c *Client) Fanout(ctx context.Context, URLs []string, bodyType string, body []byte, concurrency int) (results []BatchResult) {
+	results = make([]BatchResult, len(URLs))
+
+	if concurrency <= 0 || concurrency > len(URLs) {
+		concurrency = len(URLs)
+	}
+
+	indexes := make(chan int)
+
+	wg := &sync.WaitGroup{}
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				results[i] = c.fanoutOne(ctx, URLs[i], bodyType, body)
+			}
+		}()
+	}
+
+	for i := range URLs {
+		if ctx.Err() != nil {
+			results[i] = BatchResult{URL: URLs[i], Err: ctx.Err()}
+
+			continue
+		}
+
+		indexes <- i
+	}
+
+	close(indexes)
+
+	wg.Wait()
+
+	return
+}
+
+func (c *Client) fanoutOne(ctx context.Context, URL, bodyType string, body []byte) (result BatchResult) {
+	result.URL = URL
+
+	if result.Err = ctx.Err(); result.Err != nil {
+		return
+	}
+
+	req, err := NewRequestWithContext(ctx, methods.Post, c.withDefaultScheme(URL), body)
+	if err != nil {
+		result.Err = err
+
+		return
+	}
+
+	req.Header.Set(headers.ContentType, bodyType)
+
+	release, err := c.acquireGlobalSlot(ctx)
+	if err != nil {
+		result.Err = err
+
+		return
+	}
+
+	defer release()
+
+	result.Response, result.Err = c.Do(req)
+
+	return
+}
@@ -0,0 +1,356 @@
+package hqgohttp
+
+// This file contains code for exporting completed requests as HAR 1.2 entries, for web-debugging workflows.
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hueristiq/hqgohttp/headers"
+)
+
+// HAR is a HAR 1.2 document.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR document.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator identifies the application that created a HAR document.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is a single request/response pair of a HAR document.
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+// HARRequest is the request of a HAR entry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARPostData is the body of a HAR request.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARResponse is the response of a HAR entry.
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARContent is the body of a HAR response.
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARNameValue is a name/value pair, e.g. a header, a cookie or a query parameter.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARTimings holds the durations, in milliseconds, of the phases of a HAR entry. -1 means unknown.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// NewHAR wraps entries, e.g. read back from Options.HARWriter output, into a HAR document.
+func NewHAR(entries []HAREntry) *HAR {
+	return &HAR{
+		Log: HARLog{
+			Version: "1.2",
+			Creator: HARCreator{Name: "hqgohttp", Version: "1.0"},
+			Entries: entries,
+		},
+	}
+}
+
+// harTrace times the phases of the attempts of a request with httptrace, for their HAR entries.
+// A nil harTrace times nothing.
+type harTrace struct {
+	clock Clock
+	mutex sync.Mutex
+
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	gotConn, wroteRequest     time.Time
+	firstByte                 time.Time
+}
+
+// traceHAR traces the phases of the request when a HARWriter is set.
+func (c *Client) traceHAR(req *Request) (t *harTrace) {
+	if c.options.HARWriter == nil {
+		return
+	}
+
+	t = &harTrace{clock: c.clock}
+
+	trace := &httptrace.ClientTrace{
+		GetConn:              func(string) { t.reset() },
+		DNSStart:             func(httptrace.DNSStartInfo) { t.mark(&t.dnsStart) },
+		DNSDone:              func(httptrace.DNSDoneInfo) { t.mark(&t.dnsDone) },
+		ConnectStart:         func(string, string) { t.mark(&t.connectStart) },
+		ConnectDone:          func(string, string, error) { t.mark(&t.connectDone) },
+		TLSHandshakeStart:    func() { t.mark(&t.tlsStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { t.mark(&t.tlsDone) },
+		GotConn:              func(httptrace.GotConnInfo) { t.mark(&t.gotConn) },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { t.mark(&t.wroteRequest) },
+		GotFirstResponseByte: func() { t.mark(&t.firstByte) },
+	}
+
+	req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	return
+}
+
+// mark records the time an event happened at.
+func (t *harTrace) mark(at *time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	*at = t.clock.Now()
+}
+
+// reset forgets the events of the previous round trip, a new one starting.
+func (t *harTrace) reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.dnsStart, t.dnsDone = time.Time{}, time.Time{}
+	t.connectStart, t.connectDone = time.Time{}, time.Time{}
+	t.tlsStart, t.tlsDone = time.Time{}, time.Time{}
+	t.gotConn, t.wroteRequest = time.Time{}, time.Time{}
+	t.firstByte = time.Time{}
+}
+
+// timings returns the timings of the last round trip of an attempt, the redirect hop which got
+// the response, the attempt having taken elapsed until its response headers. Phases that
+// didn't happen, e.g. dialing on a reused connection, are -1, as per HAR. Connect includes the
+// TLS handshake, which SSL times as well.
+func (t *harTrace) timings(elapsed time.Duration) (timings HARTimings) {
+	timings = HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Wait: milliseconds(elapsed)}
+
+	if t == nil {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	between := func(start, end time.Time) float64 {
+		if start.IsZero() || end.IsZero() || end.Before(start) {
+			return -1
+		}
+
+		return milliseconds(end.Sub(start))
+	}
+
+	timings.DNS = between(t.dnsStart, t.dnsDone)
+	timings.Connect = between(t.connectStart, t.connectDone)
+	timings.SSL = between(t.tlsStart, t.tlsDone)
+
+	if timings.SSL >= 0 && timings.Connect >= 0 {
+		timings.Connect = between(t.connectStart, t.tlsDone)
+	}
+
+	if send := between(t.gotConn, t.wroteRequest); send >= 0 {
+		timings.Send = send
+	}
+
+	if wait := between(t.wroteRequest, t.firstByte); wait >= 0 {
+		timings.Wait = wait
+	}
+
+	return
+}
+
+// writeHAREntry serializes a completed request and its response to Options.HARWriter, as a
+// single line of JSON. Bodies are captured up to RespReadLimit, without consuming the response
+// body for the caller. gzip encoded bodies are decoded, binary ones are base64 encoded.
+func (c *Client) writeHAREntry(req *Request, res *http.Response, trace *harTrace, started time.Time, elapsed time.Duration) {
+	limit := c.options.RespReadLimit
+
+	if limit <= 0 {
+		limit = defaultSnapshotLimit
+	}
+
+	entry := HAREntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Time:            milliseconds(elapsed),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    req.ContentLength,
+		},
+		Response: HARResponse{
+			Status:      res.StatusCode,
+			StatusText:  http.StatusText(res.StatusCode),
+			HTTPVersion: res.Proto,
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(res.Header),
+			RedirectURL: res.Header.Get(headers.Location),
+			HeadersSize: -1,
+			BodySize:    res.ContentLength,
+		},
+		Timings: trace.timings(elapsed),
+	}
+
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			entry.Request.QueryString = append(entry.Request.QueryString, HARNameValue{Name: name, Value: value})
+		}
+	}
+
+	for _, cookie := range req.Cookies() {
+		entry.Request.Cookies = append(entry.Request.Cookies, HARNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+
+	for _, cookie := range res.Cookies() {
+		entry.Response.Cookies = append(entry.Response.Cookies, HARNameValue{Name: cookie.Name, Value: cookie.Value})
+	}
+
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			snapshot, _ := c.snapshot(body, limit)
+
+			// Finish reading reusable bodies so they rewind themselves
+			_, _ = c.discard(body)
+
+			body.Close()
+
+			text, encoding := harText(snapshot, req.Header.Get(headers.ContentEncoding))
+
+			entry.Request.PostData = &HARPostData{
+				MimeType: req.Header.Get(headers.ContentType),
+				Text:     text,
+				Encoding: encoding,
+			}
+		}
+	}
+
+	snapshot, _ := c.snapshot(res.Body, limit)
+
+	res.Body = &struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(snapshot), res.Body),
+		Closer: res.Body,
+	}
+
+	contentEncoding := ""
+
+	if !res.Uncompressed {
+		contentEncoding = res.Header.Get(headers.ContentEncoding)
+	}
+
+	text, encoding := harText(snapshot, contentEncoding)
+
+	entry.Response.Content = HARContent{
+		Size:     int64(len(snapshot)),
+		MimeType: res.Header.Get(headers.ContentType),
+		Text:     text,
+		Encoding: encoding,
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	c.harMutex.Lock()
+	defer c.harMutex.Unlock()
+
+	_, _ = c.options.HARWriter.Write(append(line, '\n'))
+}
+
+// harText returns body as HAR text, decoding gzip and base64 encoding binary data.
+func harText(body []byte, contentEncoding string) (text, encoding string) {
+	if strings.EqualFold(strings.TrimSpace(contentEncoding), "gzip") {
+		if reader, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+			// The snapshot may be truncated, keep whatever could be decoded
+			decoded, _ := io.ReadAll(reader)
+
+			body = decoded
+		}
+	}
+
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+func harHeaders(header http.Header) (pairs []HARNameValue) {
+	pairs = []HARNameValue{}
+
+	for name, values := range header {
+		for _, value := range values {
+			pairs = append(pairs, HARNameValue{Name: name, Value: value})
+		}
+	}
+
+	return
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,59 @@
+package hqgohttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestHARTimings(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	buf := &bytes.Buffer{}
+
+	options := *DefaultOptionsSingle
+	options.HARWriter = buf
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req.SkipTLSVerify())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	var entry HAREntry
+
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	timings := entry.Timings
+
+	// The server is dialed by IP, without any DNS lookup
+	if timings.DNS != -1 || timings.Connect < 0 || timings.SSL < 0 || timings.Send < 0 || timings.Wait < 0 {
+		t.Fatalf("got timings %+v, want the connection and TLS handshake timed", timings)
+	}
+
+	if timings.Connect < timings.SSL {
+		t.Fatalf("got connect %f shorter than ssl %f, want it to include the handshake", timings.Connect, timings.SSL)
+	}
+}
@@ -0,0 +1,50 @@
+package hqgohttp
+
+// This file contains the incremental hashing of response bodies.
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// hashingReadCloser hashes the bytes read from the body, reporting the hash on io.EOF.
+type hashingReadCloser struct {
+	io.ReadCloser
+	hash   hash.Hash
+	onDone func(sum string)
+	done   bool
+}
+
+func (r *hashingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+
+	r.hash.Write(p[:n])
+
+	if errors.Is(err, io.EOF) && !r.done {
+		r.done = true
+
+		r.onDone(hex.EncodeToString(r.hash.Sum(nil)))
+	}
+
+	return
+}
+
+// hashBody wraps the body of a response returned to the caller so that it is hashed into the
+// request's Metrics.BodyHash as it is read, when Options.ComputeBodyHash is set.
+func (c *Client) hashBody(req *Request, res *http.Response) {
+	if !c.options.ComputeBodyHash || res == nil || res.Body == nil {
+		return
+	}
+
+	res.Body = &hashingReadCloser{
+		ReadCloser: res.Body,
+		hash:       sha256.New(),
+		onDone: func(sum string) {
+			req.Metrics.BodyHash = sum
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package hqgohttp
+
+// This file contains the writing of request headers in a given order and casing, as net/http
+// canonicalizes and sorts them, which fingerprints the client.
+
+import (
+	"io"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/hueristiq/hqgohttp/headers"
+)
+
+// writeHeaders writes header to w, the headers listed in order first, in that order, then Host
+// and the remaining headers sorted by name. Names are matched case-insensitively against order.
+// With preserveCase, names are written as spelled in order, or as keyed in header (e.g. set with
+// header["x-custom"] rather than Set), otherwise in their canonical form.
+func writeHeaders(w io.Writer, header http.Header, order []string, preserveCase bool) (err error) {
+	names := make(map[string][]string, len(header))
+
+	for name := range header {
+		canonical := textproto.CanonicalMIMEHeaderKey(name)
+
+		names[canonical] = append(names[canonical], name)
+	}
+
+	written := make(map[string]bool, len(names))
+
+	write := func(canonical, spelling string) (err error) {
+		if written[canonical] {
+			return
+		}
+
+		written[canonical] = true
+
+		keys := names[canonical]
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name := canonical
+
+			if preserveCase {
+				name = key
+
+				if spelling != "" {
+					name = spelling
+				}
+			}
+
+			for _, value := range header[key] {
+				value = strings.TrimSpace(headerNewlineToSpace.Replace(value))
+
+				if _, err = io.WriteString(w, name+": "+value+"\r\n"); err != nil {
+					return
+				}
+			}
+		}
+
+		return
+	}
+
+	for _, name := range order {
+		if err = write(textproto.CanonicalMIMEHeaderKey(name), name); err != nil {
+			return
+		}
+	}
+
+	if err = write(headers.Host, ""); err != nil {
+		return
+	}
+
+	remaining := make([]string, 0, len(names))
+
+	for canonical := range names {
+		remaining = append(remaining, canonical)
+	}
+
+	sort.Strings(remaining)
+
+	for _, canonical := range remaining {
+		if err = write(canonical, ""); err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+var headerNewlineToSpace = strings.NewReplacer("\n", " ", "\r", " ")
@@ -0,0 +1,69 @@
+package hqgohttp
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestPerHostHeaders(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("X-Api-Key") + " " + r.Header.Get("X-Client")))
+	}))
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	options := *DefaultOptionsSingle
+	options.DefaultHeaders = http.Header{"X-Api-Key": {"default"}, "X-Client": {"hqgohttp"}}
+	options.PerHostHeaders = map[string]http.Header{
+		// Keyed by hostname, or by host and port
+		"127.0.0.1":         {"X-Api-Key": {"key-a"}},
+		"localhost:" + port: {"X-Api-Key": {"key-b"}},
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		host     string
+		explicit string
+		want     string
+	}{
+		{"127.0.0.1", "", "key-a hqgohttp"},
+		{"localhost", "", "key-b hqgohttp"},
+		{"localhost", "explicit", "explicit hqgohttp"},
+	}
+
+	for _, tt := range tests {
+		req, err := NewRequest(methods.Get, "http://"+net.JoinHostPort(tt.host, port), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tt.explicit != "" {
+			req.Header.Set("X-Api-Key", tt.explicit)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+
+		res.Body.Close()
+
+		if err != nil || string(body) != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.host, body, err, tt.want)
+		}
+	}
+}
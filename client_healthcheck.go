@@ -0,0 +1,130 @@
+package hqgohttp
+
+// This file contains the background health check of pooled connections, and the closing of the
+// client, which stops it.
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// idleConns tracks since when the connections of a client are idle in the pool, keyed by their
+// underlying net.Conn, which is all httptrace exposes of them. Connections are busy from the
+// moment a request gets them until the transport puts them back in the pool.
+type idleConns struct {
+	mutex sync.Mutex
+	conns map[net.Conn]*connIdleness
+}
+
+type connIdleness struct {
+	busy bool
+	// since is when the connection got busy or idle
+	since time.Time
+}
+
+// set records conn as busy or idle from now on.
+func (i *idleConns) set(conn net.Conn, busy bool, now time.Time) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if i.conns == nil {
+		i.conns = map[net.Conn]*connIdleness{}
+	}
+
+	i.conns[conn] = &connIdleness{busy: busy, since: now}
+}
+
+// closeStale closes the connections idle for maxIdle or more, and forgets them. The transport
+// notices the closed connections in its pool and drops them. The records of connections busy
+// for long enough to have been closed while in use are pruned.
+func (i *idleConns) closeStale(maxIdle time.Duration, now time.Time) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for conn, idleness := range i.conns {
+		switch elapsed := now.Sub(idleness.since); {
+		case !idleness.busy && elapsed >= maxIdle:
+			conn.Close()
+
+			delete(i.conns, conn)
+		case idleness.busy && elapsed > connRequestsMaxIdle:
+			delete(i.conns, conn)
+		}
+	}
+}
+
+// trackIdleConns records, with httptrace, when the connections of the request get busy and
+// idle again, for the health check.
+func (c *Client) trackIdleConns(req *Request) {
+	if c.options.ConnHealthCheckInterval <= 0 {
+		return
+	}
+
+	var conn net.Conn
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(connInfo httptrace.GotConnInfo) {
+			conn = connInfo.Conn
+
+			if TLSConn, ok := conn.(interface{ NetConn() net.Conn }); ok {
+				conn = TLSConn.NetConn()
+			}
+
+			c.idleConns.set(conn, true, c.clock.Now())
+		},
+		PutIdleConn: func(err error) {
+			if err == nil && conn != nil {
+				c.idleConns.set(conn, false, c.clock.Now())
+			}
+		},
+	}
+
+	req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+}
+
+// startHealthCheck closes, every interval until the client is closed, the pooled connections
+// idle for interval or more, which may have gone stale since their last use (e.g. closed by the
+// server or dropped by a NAT), rather than handing them to the next request. Connections used
+// recently are kept. net/http doesn't expose its idle connections, so they are not probed, and
+// HTTP/2 connections, never put back in the pool as they are shared, are left to the transport.
+func (c *Client) startHealthCheck(interval time.Duration) {
+	c.closed = make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-c.closed:
+				return
+			case <-c.clock.After(interval):
+				c.idleConns.closeStale(interval, c.clock.Now())
+			}
+		}
+	}()
+}
+
+// Close stops the background work of the client, the connection health check, and closes
+// its idle connections. Requests in flight are not interrupted. It is safe to call Close more
+// than once, the client must not be used afterwards.
+func (c *Client) Close() (err error) {
+	c.closeOnce.Do(func() {
+		if c.closed != nil {
+			close(c.closed)
+		}
+
+		// Once done, the HTTP/1.1 downgrade client can no longer be built concurrently
+		c.http11Once.Do(func() {})
+
+		for _, client := range []*http.Client{c.HTTPClient, c.HTTP2Client, c.http11} {
+			if client != nil {
+				client.CloseIdleConnections()
+			}
+		}
+
+		c.closeInsecureClients()
+	})
+
+	return
+}
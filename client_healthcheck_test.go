@@ -0,0 +1,77 @@
+package hqgohttp
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestConnHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	var conns int32
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.Start()
+
+	defer server.Close()
+
+	interval := 200 * time.Millisecond
+
+	options := *DefaultOptionsSingle
+	options.SharedTransport = DefaultHTTPPooledTransport()
+	options.ConnHealthCheckInterval = interval
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer client.Close()
+
+	get := func() {
+		req, err := NewRequest(methods.Get, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		client.drainBody(req, res)
+	}
+
+	// A connection used more often than the interval is kept across ticks
+	for i := 0; i < 10; i++ {
+		get()
+
+		time.Sleep(interval / 4)
+	}
+
+	if got := atomic.LoadInt32(&conns); got != 1 {
+		t.Fatalf("got %d connections, want the fresh one reused", got)
+	}
+
+	// A connection idle for longer is closed
+	time.Sleep(3 * interval)
+
+	get()
+
+	if got := atomic.LoadInt32(&conns); got != 2 {
+		t.Fatalf("got %d connections, want the stale one replaced", got)
+	}
+}
@@ -0,0 +1,241 @@
+package hqgohttp
+
+// This file contains a minimal HTTP/1.x transport, writing requests by hand over a raw connection
+// to control their protocol version and header order, which net/http doesn't.
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// http1Transport is an http.RoundTripper sending requests as HTTP/1.0, or HTTP/1.1, over a new
+// connection each, closed once the response body is closed.
+//
+// Limitations: proxies, HTTP/2, keep-alive and transparent decompression are not supported,
+// request bodies of unknown length are buffered to compute their Content-Length (HTTP/1.0
+// has no chunked encoding). The connection is closed once the request context is done, failing
+// the request, or the reads of its response body.
+type http1Transport struct {
+	dial      func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsConfig *tls.Config
+	// proto is the protocol of the request line, "HTTP/1.0" or "HTTP/1.1"
+	proto string
+	// headerOrder and preserveHeaderCase control how headers are written, see writeHeaders
+	headerOrder        []string
+	preserveHeaderCase bool
+}
+
+func (t *http1Transport) RoundTrip(req *http.Request) (res *http.Response, err error) {
+	body, err := http1Body(req)
+	if err != nil {
+		return
+	}
+
+	conn, err := t.connect(req)
+	if err != nil {
+		return
+	}
+
+	stopWatching := closeOnDone(req.Context(), conn)
+
+	fail := func(failure error) error {
+		stopWatching()
+		conn.Close()
+
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return ctxErr
+		}
+
+		return failure
+	}
+
+	if deadline, ok := req.Context().Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			return nil, fail(err)
+		}
+	}
+
+	if err = t.writeRequest(conn, req, body); err != nil {
+		return nil, fail(err)
+	}
+
+	if res, err = http.ReadResponse(bufio.NewReader(conn), req); err != nil {
+		return nil, fail(err)
+	}
+
+	body10 := res.Body
+
+	res.Body = &struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: body10,
+		Closer: closerFunc(func() error {
+			stopWatching()
+			body10.Close()
+
+			return conn.Close()
+		}),
+	}
+
+	return
+}
+
+// closeOnDone closes conn once ctx is done, until the returned function is called.
+func closeOnDone(ctx context.Context, conn net.Conn) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	stopped := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stopped:
+		}
+	}()
+
+	once := &sync.Once{}
+
+	return func() {
+		once.Do(func() {
+			close(stopped)
+		})
+	}
+}
+
+// connect dials the request host, over TLS for https URLs.
+func (t *http1Transport) connect(req *http.Request) (conn net.Conn, err error) {
+	host := req.URL.Hostname()
+	port := req.URL.Port()
+
+	if port == "" {
+		port = "80"
+
+		if req.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	if conn, err = t.dial(req.Context(), "tcp", net.JoinHostPort(host, port)); err != nil {
+		return
+	}
+
+	if req.URL.Scheme != "https" {
+		return
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if t.tlsConfig != nil {
+		config = t.tlsConfig.Clone()
+	}
+
+	if config.ServerName == "" {
+		config.ServerName = host
+	}
+
+	TLSConn := tls.Client(conn, config)
+
+	if err = TLSConn.HandshakeContext(req.Context()); err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return TLSConn, nil
+}
+
+// http1Body reads the request body whole, as its length must be sent upfront.
+func http1Body(req *http.Request) (body []byte, err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	defer req.Body.Close()
+
+	return io.ReadAll(req.Body)
+}
+
+func (t *http1Transport) writeRequest(conn net.Conn, req *http.Request, body []byte) (err error) {
+	w := bufio.NewWriter(conn)
+
+	host := req.Host
+
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	fmt.Fprintf(w, "%s %s %s\r\n", req.Method, req.URL.RequestURI(), t.proto)
+
+	header := req.Header.Clone()
+
+	header.Del(headers.Host)
+	header.Set(headers.Host, host)
+	header.Del(headers.ContentLength)
+	header.Del(headers.TransferEncoding)
+	header.Set(headers.Connection, "close")
+
+	if len(body) > 0 || (req.Method != methods.Get && req.Method != methods.Head) {
+		header.Set(headers.ContentLength, fmt.Sprint(len(body)))
+	}
+
+	if header.Get(headers.UserAgent) == "" {
+		header.Set(headers.UserAgent, "Go-http-client/"+strings.TrimPrefix(t.proto, "HTTP/"))
+	}
+
+	if err = writeHeaders(w, header, t.headerOrder, t.preserveHeaderCase); err != nil {
+		return
+	}
+
+	if _, err = w.WriteString("\r\n"); err != nil {
+		return
+	}
+
+	if _, err = io.Copy(w, bytes.NewReader(body)); err != nil {
+		return
+	}
+
+	return w.Flush()
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error {
+	return f()
+}
+
+// useHTTP1 replaces the transport of the client with one writing requests by hand: as HTTP/1.0
+// with ForceHTTP10, HTTP/1.1 otherwise, with the header order and casing of the options.
+func useHTTP1(client *http.Client, options *Options) {
+	transport := &http1Transport{
+		dial:               dialContext(options),
+		proto:              "HTTP/1.1",
+		headerOrder:        options.RawHeaderOrder,
+		preserveHeaderCase: options.PreserveHeaderCase,
+	}
+
+	if options.ForceHTTP10 {
+		transport.proto = "HTTP/1.0"
+	}
+
+	if base, ok := client.Transport.(*http.Transport); ok {
+		transport.tlsConfig = base.TLSClientConfig
+	}
+
+	client.Transport = transport
+}
@@ -0,0 +1,69 @@
+package hqgohttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTP1TransportCancel(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/body" {
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+		}
+
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	transport := &http1Transport{dial: (&net.Dialer{}).DialContext, proto: "HTTP/1.0"}
+
+	t.Run("headers", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/headers", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %v, want the request canceled", err)
+		}
+	})
+
+	t.Run("body", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/body", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer res.Body.Close()
+
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		if _, err := io.ReadAll(res.Body); err == nil {
+			t.Fatal("got the body read whole, want the read interrupted")
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package hqgohttp
+
+// This file contains the conversion of internationalized domain names (IDN) to the ASCII
+// (punycode) form used in the Host header and for SNI.
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalidIDN is returned when a URL host is an invalid internationalized domain name.
+var ErrInvalidIDN = errors.New("invalid internationalized domain name")
+
+// toASCIIHost converts the host name of a "host" or "host:port" to punycode.
+// ASCII hosts, e.g. IP addresses, are returned as is.
+func toASCIIHost(host string) (ASCIIHost string, err error) {
+	if isASCII(host) {
+		return host, nil
+	}
+
+	name, port := host, ""
+
+	if h, p, splitErr := net.SplitHostPort(host); splitErr == nil {
+		name, port = h, p
+	}
+
+	if ASCIIHost, err = idna.Lookup.ToASCII(name); err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrInvalidIDN, name, err)
+	}
+
+	if port != "" {
+		ASCIIHost = net.JoinHostPort(ASCIIHost, port)
+	}
+
+	return
+}
+
+// DisplayURL returns the URL of the request with its host in Unicode form, e.g.
+// https://münchen.de/ rather than https://xn--mnchen-3ya.de/, for display and logging.
+func (r *Request) DisplayURL() string {
+	host, err := idna.Display.ToUnicode(r.URL.Hostname())
+	if err != nil || r.URL.Host == "" || r.URL.Opaque != "" {
+		return r.URL.String()
+	}
+
+	if port := r.URL.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	// url.URL escapes non-ASCII hosts, write the host by hand
+	rest := *r.URL
+
+	rest.Scheme = ""
+	rest.User = nil
+	rest.Host = ""
+
+	var display strings.Builder
+
+	if r.URL.Scheme != "" {
+		display.WriteString(r.URL.Scheme + ":")
+	}
+
+	display.WriteString("//")
+
+	if r.URL.User != nil {
+		display.WriteString(r.URL.User.String() + "@")
+	}
+
+	display.WriteString(host)
+	display.WriteString(rest.String())
+
+	return display.String()
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,46 @@
+package hqgohttp
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestIDNHosts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		URL     string
+		host    string
+		display string
+	}{
+		{"https://münchen.de/straße?q=1", "xn--mnchen-3ya.de", "https://münchen.de/stra%C3%9Fe?q=1"},
+		{"https://BÜCHER.example:8443/", "xn--bcher-kva.example:8443", "https://bücher.example:8443/"},
+		{"http://例え.テスト/", "xn--r8jz45g.xn--zckzah", "http://例え.テスト/"},
+		// ASCII hosts are left as is
+		{"https://example.com/", "example.com", "https://example.com/"},
+		{"http://[::1]:8080/", "[::1]:8080", "http://[::1]:8080/"},
+	}
+
+	for _, tt := range tests {
+		req, err := NewRequest(methods.Get, tt.URL, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.URL, err)
+
+			continue
+		}
+
+		if req.URL.Host != tt.host {
+			t.Errorf("%s: got host %q, want %q", tt.URL, req.URL.Host, tt.host)
+		}
+
+		if display := req.DisplayURL(); display != tt.display {
+			t.Errorf("%s: got display URL %q, want %q", tt.URL, display, tt.display)
+		}
+	}
+
+	if _, err := NewRequest(methods.Get, "https://xn--a.münchen.de/", nil); !errors.Is(err, ErrInvalidIDN) {
+		t.Fatalf("got %v, want the malformed IDN rejected", err)
+	}
+}
@@ -0,0 +1,94 @@
+package hqgohttp
+
+// This file contains the skipping of TLS certificate verification for single requests.
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+)
+
+// SkipTLSVerify makes Do skip the verification of the TLS certificate of the server for this
+// request only, and returns the request for chaining. It is much safer than disabling the
+// verification client-wide, but still leaves the request open to man-in-the-middle attacks.
+//
+// TLS is configured per connection, so such requests go through a transport of their own,
+// cloned from the client's, whose connections are never shared with verified requests in
+// either direction. With keep-alives, these connections are pooled apart, doubling the idle
+// connections kept to hosts reached both ways. Requests through a custom transport other than
+// an *http.Transport can't be rerouted, and are verified as usual. PinnedPublicKeySHA256 pins
+// are still enforced.
+func (r *Request) SkipTLSVerify() *Request {
+	return r.WithContext(context.WithValue(r.Context(), skipTLSVerifyKey{}, true))
+}
+
+// tlsClient returns client, or its TLS verification skipping counterpart if req asks for it.
+func (c *Client) tlsClient(req *Request, client *http.Client) *http.Client {
+	if skip, _ := req.Context().Value(skipTLSVerifyKey{}).(bool); !skip || client == nil {
+		return client
+	}
+
+	c.insecureMutex.Lock()
+	defer c.insecureMutex.Unlock()
+
+	if insecure, ok := c.insecureClients[client]; ok {
+		return insecure
+	}
+
+	var transport http.RoundTripper
+
+	switch base := client.Transport.(type) {
+	case *http.Transport:
+		clone := base.Clone()
+
+		clone.TLSClientConfig = insecureTLSConfig(clone.TLSClientConfig)
+
+		transport = clone
+	case *http1Transport:
+		clone := *base
+
+		clone.tlsConfig = insecureTLSConfig(clone.tlsConfig)
+
+		transport = &clone
+	default:
+		return client
+	}
+
+	insecure := &http.Client{
+		Transport:     transport,
+		CheckRedirect: client.CheckRedirect,
+		Jar:           client.Jar,
+		Timeout:       client.Timeout,
+	}
+
+	if c.insecureClients == nil {
+		c.insecureClients = map[*http.Client]*http.Client{}
+	}
+
+	c.insecureClients[client] = insecure
+
+	return insecure
+}
+
+// insecureTLSConfig returns a copy of config skipping certificate verification.
+func insecureTLSConfig(config *tls.Config) *tls.Config {
+	if config == nil {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	} else {
+		config = config.Clone()
+	}
+
+	config.InsecureSkipVerify = true
+
+	return config
+}
+
+// closeInsecureClients closes the idle connections of the TLS verification skipping clients.
+func (c *Client) closeInsecureClients() {
+	c.insecureMutex.Lock()
+	defer c.insecureMutex.Unlock()
+
+	for _, client := range c.insecureClients {
+		client.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,139 @@
+package hqgohttp
+
+// This file contains the detection of responses intercepted on their way, by a captive portal or
+// a transparent proxy, rather than sent by the target.
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hueristiq/hqgohttp/status"
+	"golang.org/x/net/publicsuffix"
+)
+
+// WithExpectedStatus returns a copy of ctx declaring the status code expected in answer to the
+// requests using it, e.g. 204 for connectivity probes, or 404 for a path known not to exist, which
+// DetectInterception reports other status codes as interceptions for.
+func WithExpectedStatus(ctx context.Context, code int) context.Context {
+	return context.WithValue(ctx, expectedStatusKey{}, code)
+}
+
+// DetectInterception reports whether resp, to a request for expectedHost ("host[:port]", the
+// port being ignored), looks intercepted, with the reason why. In order, it checks for:
+//
+//   - a 511 Network Authentication Required status, which captive portals answer with.
+//   - a response from another site, the request having been redirected, e.g. to a portal. Hosts
+//     of the same registrable domain (eTLD+1), e.g. example.com and www.example.com, are the
+//     same site.
+//   - a plain HTTP response to an HTTPS request, the request having been downgraded.
+//   - a TLS certificate not valid for expectedHost, as presented by intercepting proxies. Only
+//     responses to requests skipping the verification (SkipTLSVerify) or pinning keys can have
+//     one, others fail the handshake.
+//   - a status code other than the one set with WithExpectedStatus, if any.
+func DetectInterception(resp *http.Response, expectedHost string) (intercepted bool, reason string) {
+	if resp == nil {
+		return
+	}
+
+	if resp.StatusCode == status.NetworkAuthenticationRequired {
+		return true, "network authentication required (511), a captive portal answered"
+	}
+
+	expected := hostWithoutPort(expectedHost)
+
+	req := resp.Request
+
+	if req == nil {
+		return
+	}
+
+	if host := req.URL.Hostname(); !sameSite(host, expected) {
+		return true, fmt.Sprintf("redirected to %s instead of %s", host, expected)
+	}
+
+	first := req
+
+	for first.Response != nil && first.Response.Request != nil {
+		first = first.Response.Request
+	}
+
+	if first.URL.Scheme == "https" && req.URL.Scheme == "http" {
+		return true, fmt.Sprintf("downgraded from HTTPS to plain HTTP (%s)", req.URL.Redacted())
+	}
+
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		leaf := resp.TLS.PeerCertificates[0]
+
+		if err := leaf.VerifyHostname(expected); err != nil {
+			return true, fmt.Sprintf("certificate for %s (issued by %s) not valid for %s", certificateNames(leaf), leaf.Issuer, expected)
+		}
+	}
+
+	if code, ok := req.Context().Value(expectedStatusKey{}).(int); ok && resp.StatusCode != code {
+		return true, fmt.Sprintf("status %d instead of the expected %d", resp.StatusCode, code)
+	}
+
+	return
+}
+
+// detectInterception calls Options.InterceptionHook with res, to req, if it looks intercepted.
+func (c *Client) detectInterception(req *Request, res *http.Response) {
+	if c.options.InterceptionHook == nil {
+		return
+	}
+
+	if intercepted, reason := DetectInterception(res, req.URL.Host); intercepted {
+		c.options.InterceptionHook(res, reason)
+	}
+}
+
+// certificateNames describes the names a certificate is valid for.
+func certificateNames(cert *x509.Certificate) string {
+	if len(cert.DNSNames) > 0 {
+		return strings.Join(cert.DNSNames, ", ")
+	}
+
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+
+	return cert.Subject.String()
+}
+
+// sameSite reports whether the hosts a and b are the same, or share their registrable domain.
+func sameSite(a, b string) bool {
+	a, b = strings.ToLower(strings.TrimSuffix(a, ".")), strings.ToLower(strings.TrimSuffix(b, "."))
+
+	if a == b {
+		return true
+	}
+
+	if net.ParseIP(a) != nil || net.ParseIP(b) != nil {
+		return false
+	}
+
+	siteA, err := publicsuffix.EffectiveTLDPlusOne(a)
+	if err != nil {
+		return false
+	}
+
+	siteB, err := publicsuffix.EffectiveTLDPlusOne(b)
+	if err != nil {
+		return false
+	}
+
+	return siteA == siteB
+}
+
+// hostWithoutPort returns host without its port, if any, and IPv6 brackets.
+func hostWithoutPort(host string) string {
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		return hostname
+	}
+
+	return strings.Trim(host, "[]")
+}
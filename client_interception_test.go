@@ -0,0 +1,38 @@
+package hqgohttp
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestDetectInterceptionRedirects(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expected    string
+		final       string
+		intercepted bool
+	}{
+		{"example.com", "https://example.com/", false},
+		{"example.com:443", "https://WWW.example.com/", false},
+		{"www.example.com", "https://example.com/", false},
+		{"a.example.co.uk", "https://b.example.co.uk/", false},
+		{"example.com", "https://portal.example.net/login", true},
+		{"example.co.uk", "https://other.co.uk/", true},
+		{"127.0.0.1", "http://127.0.0.2/", true},
+	}
+
+	for _, tt := range tests {
+		final, err := url.Parse(tt.final)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := &http.Response{StatusCode: http.StatusOK, Request: &http.Request{URL: final}}
+
+		if intercepted, reason := DetectInterception(res, tt.expected); intercepted != tt.intercepted {
+			t.Errorf("%s to %s: got intercepted %v (%s), want %v", tt.expected, tt.final, intercepted, reason, tt.intercepted)
+		}
+	}
+}
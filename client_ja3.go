@@ -0,0 +1,227 @@
+package hqgohttp
+
+// This file contains the computation of the JA3 fingerprint of the TLS ClientHello the client sends.
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// errMalformedClientHello is returned when a captured ClientHello can't be parsed.
+var errMalformedClientHello = errors.New("malformed ClientHello")
+
+// JA3 returns the JA3 fingerprint string of the ClientHello the client sends to named hosts:
+// "version,ciphers,extensions,curves,point formats", without GREASE values. It is computed by
+// handshaking over an in-memory connection with a copy of the client's transport, ALPN
+// included, as set up for HTTP/2 when the transport attempts it.
+//
+// crypto/tls builds ClientHellos deterministically from their configuration, so every
+// handshake of the client, across reconnects and pool churn, presents this same fingerprint.
+// Hosts dialed by IP address send no server name extension, and resumed sessions, with a
+// ClientSessionCache, a pre-shared key extension, which change the fingerprint.
+func (c *Client) JA3() string {
+	hello, err := c.captureClientHello()
+	if err != nil {
+		return ""
+	}
+
+	ja3, err := parseJA3(hello)
+	if err != nil {
+		return ""
+	}
+
+	return ja3
+}
+
+// captureClientHello returns the handshake message of the ClientHello of the client, as sent
+// by a copy of its transport dialing an in-memory connection, so that the ALPN protocols the
+// transport sets up on its own are included.
+func (c *Client) captureClientHello() (hello []byte, err error) {
+	transport := &http.Transport{TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12}}
+
+	if base, ok := c.HTTPClient.Transport.(*http.Transport); ok {
+		transport = base.Clone()
+	}
+
+	host := "example.com"
+
+	if transport.TLSClientConfig != nil && transport.TLSClientConfig.ServerName != "" {
+		host = transport.TLSClientConfig.ServerName
+	}
+
+	clientConn, serverConn := net.Pipe()
+
+	transport.Proxy = nil
+	transport.DialTLSContext = nil
+	transport.DialTLS = nil //nolint:staticcheck // Deprecated, but still used when set
+	transport.DialContext = func(context.Context, string, string) (net.Conn, error) {
+		return clientConn, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/", nil)
+		if err != nil {
+			return
+		}
+
+		// The handshake fails once the server side closes, only the ClientHello matters
+		if res, err := transport.RoundTrip(req); err == nil {
+			res.Body.Close()
+		}
+	}()
+
+	defer func() {
+		cancel()
+		serverConn.Close()
+		clientConn.Close()
+		<-done
+		transport.CloseIdleConnections()
+	}()
+
+	header := make([]byte, 5)
+
+	if _, err = io.ReadFull(serverConn, header); err != nil {
+		return
+	}
+
+	// A handshake record
+	if header[0] != 22 {
+		return nil, errMalformedClientHello
+	}
+
+	hello = make([]byte, binary.BigEndian.Uint16(header[3:5]))
+
+	_, err = io.ReadFull(serverConn, hello)
+
+	return
+}
+
+// parseJA3 returns the JA3 string of hello, a ClientHello handshake message.
+func parseJA3(hello []byte) (ja3 string, err error) {
+	r := &helloReader{data: hello}
+
+	// Handshake type and length
+	if r.uint8() != 1 {
+		return "", errMalformedClientHello
+	}
+
+	r.skip(3)
+
+	version := r.uint16()
+
+	// Random
+	r.skip(32)
+	r.skip(int(r.uint8()))
+
+	ciphers := r.uint16s(int(r.uint16()) / 2)
+
+	// Compression methods
+	r.skip(int(r.uint8()))
+
+	var extensions, curves, pointFormats []uint16
+
+	end := r.offset + int(r.uint16())
+
+	for !r.failed && r.offset < end {
+		extension := r.uint16()
+		length := int(r.uint16())
+		next := r.offset + length
+
+		extensions = append(extensions, extension)
+
+		switch extension {
+		case 10: // supported_groups
+			curves = r.uint16s(int(r.uint16()) / 2)
+		case 11: // ec_point_formats
+			for n := int(r.uint8()); n > 0; n-- {
+				pointFormats = append(pointFormats, uint16(r.uint8()))
+			}
+		}
+
+		r.offset = next
+	}
+
+	if r.failed || r.offset > len(hello) {
+		return "", errMalformedClientHello
+	}
+
+	ja3 = strings.Join([]string{
+		strconv.Itoa(int(version)),
+		joinJA3(ciphers),
+		joinJA3(extensions),
+		joinJA3(curves),
+		joinJA3(pointFormats),
+	}, ",")
+
+	return
+}
+
+// joinJA3 joins values with "-", leaving GREASE values (RFC 8701) out.
+func joinJA3(values []uint16) string {
+	parts := make([]string, 0, len(values))
+
+	for _, value := range values {
+		if value&0x0f0f == 0x0a0a && value>>8 == value&0xff {
+			continue
+		}
+
+		parts = append(parts, fmt.Sprint(value))
+	}
+
+	return strings.Join(parts, "-")
+}
+
+// helloReader reads big endian values from a ClientHello. Reads past the end set failed.
+type helloReader struct {
+	data   []byte
+	offset int
+	failed bool
+}
+
+func (r *helloReader) take(n int) (b []byte) {
+	if r.failed || r.offset+n > len(r.data) {
+		r.failed = true
+
+		return make([]byte, n)
+	}
+
+	b = r.data[r.offset : r.offset+n]
+
+	r.offset += n
+
+	return
+}
+
+func (r *helloReader) skip(n int) {
+	r.take(n)
+}
+
+func (r *helloReader) uint8() uint8 {
+	return r.take(1)[0]
+}
+
+func (r *helloReader) uint16() uint16 {
+	return binary.BigEndian.Uint16(r.take(2))
+}
+
+func (r *helloReader) uint16s(n int) (values []uint16) {
+	for ; n > 0 && !r.failed; n-- {
+		values = append(values, r.uint16())
+	}
+
+	return
+}
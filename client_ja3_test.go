@@ -0,0 +1,97 @@
+package hqgohttp
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// readClientHello reads the ClientHello handshake message sent over conn.
+func readClientHello(conn net.Conn) (hello []byte, err error) {
+	header := make([]byte, 5)
+
+	if _, err = io.ReadFull(conn, header); err != nil {
+		return
+	}
+
+	hello = make([]byte, binary.BigEndian.Uint16(header[3:5]))
+
+	_, err = io.ReadFull(conn, hello)
+
+	return
+}
+
+func TestJA3Stable(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	const dials = 5
+
+	fingerprints := make(chan string, dials)
+
+	go func() {
+		for i := 0; i < dials; i++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			// Fail the handshake once the ClientHello is captured
+			hello, err := readClientHello(conn)
+
+			conn.Close()
+
+			ja3 := ""
+
+			if err == nil {
+				ja3, _ = parseJA3(hello)
+			}
+
+			fingerprints <- ja3
+		}
+	}()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 0
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := client.JA3()
+
+	if want == "" {
+		t.Fatal("got no JA3")
+	}
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	for i := 0; i < dials; i++ {
+		req, err := NewRequest(methods.Get, "https://localhost:"+port, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = client.Do(req); err == nil {
+			t.Fatal("got no error, want the handshake to fail")
+		}
+
+		if got := <-fingerprints; got != want {
+			t.Fatalf("dial %d presented %q, want %q", i, got, want)
+		}
+
+		if got := client.JA3(); got != want {
+			t.Fatalf("got JA3 %q after %d dials, want it stable", got, i+1)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package hqgohttp
+
+// This file contains code for decoding JSON responses while keeping their raw bytes.
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DoJSONRaw executes the request, reads the response body whole, up to RespReadLimit bytes, and
+// unmarshals it into out, returning the raw bytes along with the response, whose body is closed.
+// The raw bytes are returned even when unmarshaling fails, so that malformed bodies can be
+// inspected. A body larger than the limit isn't unmarshaled: its first RespReadLimit bytes are
+// returned with ErrBodyLimitExceeded.
+func (c *Client) DoJSONRaw(req *Request, out interface{}) (raw []byte, res *http.Response, err error) {
+	if res, err = c.Do(req); err != nil {
+		return
+	}
+
+	limit := c.options.RespReadLimit
+
+	if limit <= 0 {
+		limit = defaultSnapshotLimit
+	}
+
+	if raw, err = ReadBodyBytes(res, limit); err != nil {
+		return
+	}
+
+	err = json.Unmarshal(raw, out)
+
+	return
+}
@@ -0,0 +1,75 @@
+package hqgohttp
+
+// This file contains code for consuming responses made of a single JSON array element by element,
+// as it streams in, rather than buffering the whole array.
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// DoJSONStream executes the request and decodes its body, a JSON array, one element at a time
+// into elem, which must be a non-nil pointer, calling fn after each element. elem is zeroed
+// before every element, so that no field leaks from one element to the next. An error from
+// fn stops the stream and is returned. The body is closed once the array ends, on error, or
+// when the request context is done.
+func (c *Client) DoJSONStream(req *Request, elem interface{}, fn func() error) (err error) {
+	target := reflect.ValueOf(elem)
+
+	if target.Kind() != reflect.Pointer || target.IsNil() {
+		return fmt.Errorf("elem must be a non-nil pointer, got %T", elem)
+	}
+
+	release, err := c.acquireGlobalSlot(req.Context())
+	if err != nil {
+		return
+	}
+
+	defer release()
+
+	res, err := c.Do(req)
+	if err != nil {
+		return
+	}
+
+	defer res.Body.Close()
+
+	decoder := json.NewDecoder(res.Body)
+
+	if err = expectDelim(decoder, '['); err != nil {
+		return
+	}
+
+	for decoder.More() {
+		if err = req.Context().Err(); err != nil {
+			return
+		}
+
+		target.Elem().Set(reflect.Zero(target.Elem().Type()))
+
+		if err = decoder.Decode(elem); err != nil {
+			return
+		}
+
+		if err = fn(); err != nil {
+			return
+		}
+	}
+
+	return expectDelim(decoder, ']')
+}
+
+// expectDelim reads the next token of the decoder, which must be the delim delimiter.
+func expectDelim(decoder *json.Decoder, delim json.Delim) (err error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return
+	}
+
+	if got, ok := token.(json.Delim); !ok || got != delim {
+		return fmt.Errorf("expected JSON %q, got %v", delim, token)
+	}
+
+	return
+}
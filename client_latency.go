@@ -0,0 +1,264 @@
+package hqgohttp
+
+// This file contains the recording of request latencies across many requests, and their percentiles.
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyPhase is a phase of the requests whose latencies a LatencyRecorder records.
+type LatencyPhase uint8
+
+const (
+	// PhaseTotal is the duration of Do calls, retries included, until the response headers
+	PhaseTotal LatencyPhase = iota
+	// PhaseDNS is the duration of the DNS lookups
+	PhaseDNS
+	// PhaseConnect is the duration of the TCP connections
+	PhaseConnect
+	// PhaseTLS is the duration of the TLS handshakes
+	PhaseTLS
+	// PhaseWait is the time to first byte, from the request written to the response's first byte
+	PhaseWait
+
+	latencyPhases
+)
+
+// LatencyRecorder records the latencies of the requests of the clients it is set on, as
+// Options.LatencyRecorder, in total and per phase, and computes their percentiles. Phases are
+// summed over the attempts of a request, and only recorded for requests that went through them:
+// reused connections have no DNS, connect or TLS phases. Memory is capped by keeping a uniform
+// random sample of each phase's durations (reservoir sampling), the percentiles being estimated
+// from it past the sample size. It is threadsafe.
+type LatencyRecorder struct {
+	mutex      sync.Mutex
+	size       int
+	reservoirs [latencyPhases]reservoir
+}
+
+// reservoir is a uniform random sample of the durations of a phase.
+type reservoir struct {
+	samples []time.Duration
+	count   uint64
+}
+
+// LatencyPercentiles are the percentiles of the latencies of a phase.
+type LatencyPercentiles struct {
+	// Count is the number of latencies recorded
+	Count uint64
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+// LatencySnapshot holds the percentiles of every phase at a point in time.
+type LatencySnapshot struct {
+	Total   LatencyPercentiles
+	DNS     LatencyPercentiles
+	Connect LatencyPercentiles
+	TLS     LatencyPercentiles
+	Wait    LatencyPercentiles
+}
+
+// NewLatencyRecorder returns a recorder keeping up to size durations per phase, 10000 if size
+// isn't positive.
+func NewLatencyRecorder(size int) *LatencyRecorder {
+	if size <= 0 {
+		size = defaultLatencyReservoirSize
+	}
+
+	return &LatencyRecorder{size: size}
+}
+
+// Record records a duration of phase.
+func (r *LatencyRecorder) Record(phase LatencyPhase, d time.Duration) {
+	if phase >= latencyPhases {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	reservoir := &r.reservoirs[phase]
+
+	reservoir.count++
+
+	if len(reservoir.samples) < r.size {
+		reservoir.samples = append(reservoir.samples, d)
+
+		return
+	}
+
+	// Keep the n-th duration with a probability of size/n
+	if i := cryptoRandInt(int(reservoir.count)); i < r.size {
+		reservoir.samples[i] = d
+	}
+}
+
+// Percentile returns the p-th percentile, within [0, 100], of the total latencies, zero if
+// none were recorded.
+func (r *LatencyRecorder) Percentile(p float64) time.Duration {
+	return r.PhasePercentile(PhaseTotal, p)
+}
+
+// PhasePercentile returns the p-th percentile, within [0, 100], of the latencies of phase,
+// zero if none were recorded.
+func (r *LatencyRecorder) PhasePercentile(phase LatencyPhase, p float64) time.Duration {
+	if phase >= latencyPhases {
+		return 0
+	}
+
+	r.mutex.Lock()
+	samples := append([]time.Duration(nil), r.reservoirs[phase].samples...)
+	r.mutex.Unlock()
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i] < samples[j]
+	})
+
+	return percentile(samples, p)
+}
+
+// Snapshot returns the 50th, 90th and 99th percentiles of every phase.
+func (r *LatencyRecorder) Snapshot() (snapshot LatencySnapshot) {
+	phases := [latencyPhases]*LatencyPercentiles{
+		PhaseTotal:   &snapshot.Total,
+		PhaseDNS:     &snapshot.DNS,
+		PhaseConnect: &snapshot.Connect,
+		PhaseTLS:     &snapshot.TLS,
+		PhaseWait:    &snapshot.Wait,
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for phase, percentiles := range phases {
+		reservoir := r.reservoirs[phase]
+
+		samples := append([]time.Duration(nil), reservoir.samples...)
+
+		sort.Slice(samples, func(i, j int) bool {
+			return samples[i] < samples[j]
+		})
+
+		*percentiles = LatencyPercentiles{
+			Count: reservoir.count,
+			P50:   percentile(samples, 50),
+			P90:   percentile(samples, 90),
+			P99:   percentile(samples, 99),
+		}
+	}
+
+	return
+}
+
+// Reset forgets the latencies recorded so far, e.g. between load test runs.
+func (r *LatencyRecorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.reservoirs = [latencyPhases]reservoir{}
+}
+
+// percentile returns the p-th percentile of sorted, by the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	switch {
+	case p <= 0:
+		return sorted[0]
+	case p >= 100:
+		return sorted[len(sorted)-1]
+	}
+
+	rank := int(p / 100 * float64(len(sorted)))
+
+	if float64(rank) < p/100*float64(len(sorted)) {
+		rank++
+	}
+
+	return sorted[rank-1]
+}
+
+// recordLatencies traces the phases of the request, and returns the function recording them,
+// along with the total latency, to the LatencyRecorder of the client, if any.
+func (c *Client) recordLatencies(req *Request) (record func()) {
+	recorder := c.options.LatencyRecorder
+
+	if recorder == nil {
+		return func() {}
+	}
+
+	var mutex sync.Mutex
+
+	var durations [latencyPhases]time.Duration
+
+	var traced [latencyPhases]bool
+
+	var starts [latencyPhases]time.Time
+
+	start := func(phase LatencyPhase) func() {
+		return func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			starts[phase] = c.clock.Now()
+		}
+	}
+
+	done := func(phase LatencyPhase) func() {
+		return func() {
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			if starts[phase].IsZero() {
+				return
+			}
+
+			durations[phase] += c.clock.Now().Sub(starts[phase])
+			traced[phase] = true
+			starts[phase] = time.Time{}
+		}
+	}
+
+	startDNS, doneDNS := start(PhaseDNS), done(PhaseDNS)
+	startConnect, doneConnect := start(PhaseConnect), done(PhaseConnect)
+	startTLS, doneTLS := start(PhaseTLS), done(PhaseTLS)
+	startWait, doneWait := start(PhaseWait), done(PhaseWait)
+
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { startDNS() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { doneDNS() },
+		ConnectStart:         func(string, string) { startConnect() },
+		ConnectDone:          func(string, string, error) { doneConnect() },
+		TLSHandshakeStart:    startTLS,
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { doneTLS() },
+		WroteRequest:         func(httptrace.WroteRequestInfo) { startWait() },
+		GotFirstResponseByte: doneWait,
+	}
+
+	req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	begin := c.clock.Now()
+
+	return func() {
+		recorder.Record(PhaseTotal, c.clock.Now().Sub(begin))
+
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		for phase := PhaseDNS; phase < latencyPhases; phase++ {
+			if traced[phase] {
+				recorder.Record(phase, durations[phase])
+			}
+		}
+	}
+}
+
+const defaultLatencyReservoirSize = 10000
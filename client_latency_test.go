@@ -0,0 +1,168 @@
+package hqgohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestLatencyRecorderPercentiles(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewLatencyRecorder(0)
+
+	if got := recorder.Percentile(50); got != 0 {
+		t.Fatalf("got p50 %s without latencies, want 0", got)
+	}
+
+	// Recorded concurrently and out of order
+	wg := &sync.WaitGroup{}
+
+	for i := 100; i > 0; i-- {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			recorder.Record(PhaseTotal, time.Duration(i)*time.Millisecond)
+		}(i)
+	}
+
+	wg.Wait()
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{1, time.Millisecond},
+		{50, 50 * time.Millisecond},
+		{90, 90 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{99.5, 100 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := recorder.Percentile(tt.p); got != tt.want {
+			t.Errorf("got p%v %s, want %s", tt.p, got, tt.want)
+		}
+	}
+
+	recorder.Record(PhaseWait, 7*time.Millisecond)
+
+	snapshot := recorder.Snapshot()
+
+	want := LatencyPercentiles{Count: 100, P50: 50 * time.Millisecond, P90: 90 * time.Millisecond, P99: 99 * time.Millisecond}
+
+	if snapshot.Total != want {
+		t.Errorf("got total %+v, want %+v", snapshot.Total, want)
+	}
+
+	want = LatencyPercentiles{Count: 1, P50: 7 * time.Millisecond, P90: 7 * time.Millisecond, P99: 7 * time.Millisecond}
+
+	if snapshot.Wait != want {
+		t.Errorf("got wait %+v, want %+v", snapshot.Wait, want)
+	}
+
+	if snapshot.DNS != (LatencyPercentiles{}) {
+		t.Errorf("got DNS %+v without latencies, want zero", snapshot.DNS)
+	}
+
+	recorder.Reset()
+
+	if snapshot := recorder.Snapshot(); snapshot.Total.Count != 0 || snapshot.Total.P99 != 0 {
+		t.Errorf("got total %+v after Reset, want zero", snapshot.Total)
+	}
+}
+
+func TestLatencyRecorderReservoir(t *testing.T) {
+	t.Parallel()
+
+	recorder := NewLatencyRecorder(10)
+
+	for i := 1; i <= 1000; i++ {
+		recorder.Record(PhaseTotal, time.Duration(i))
+	}
+
+	if n := len(recorder.reservoirs[PhaseTotal].samples); n != 10 {
+		t.Fatalf("got %d samples kept, want 10", n)
+	}
+
+	if count := recorder.Snapshot().Total.Count; count != 1000 {
+		t.Fatalf("got count %d, want 1000", count)
+	}
+
+	if p100 := recorder.Percentile(100); p100 < 1 || p100 > 1000 {
+		t.Fatalf("got p100 %d out of the durations recorded", p100)
+	}
+}
+
+func TestLatencyRecorderOption(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	recorder := NewLatencyRecorder(0)
+
+	options := *DefaultOptionsSingle
+	options.LatencyRecorder = recorder
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const requests = 5
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			req, err := NewRequest(methods.Get, server.URL, nil)
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			res, err := client.Do(req)
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			res.Body.Close()
+		}()
+	}
+
+	wg.Wait()
+
+	snapshot := recorder.Snapshot()
+
+	if snapshot.Total.Count != requests || snapshot.Wait.Count != requests {
+		t.Fatalf("got %d total and %d wait latencies, want %d", snapshot.Total.Count, snapshot.Wait.Count, requests)
+	}
+
+	// Keep-alives are disabled: every request connects, to an IP without DNS lookups
+	if snapshot.Connect.Count != requests || snapshot.DNS.Count != 0 || snapshot.TLS.Count != 0 {
+		t.Fatalf("got %d connect, %d DNS and %d TLS latencies, want %d, 0 and 0",
+			snapshot.Connect.Count, snapshot.DNS.Count, snapshot.TLS.Count, requests)
+	}
+
+	if snapshot.Wait.P50 < 10*time.Millisecond || snapshot.Total.P50 < snapshot.Wait.P50 {
+		t.Fatalf("got wait p50 %s and total p50 %s, want at least 10ms and the wait", snapshot.Wait.P50, snapshot.Total.P50)
+	}
+}
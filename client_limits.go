@@ -0,0 +1,136 @@
+package hqgohttp
+
+// This file contains the size limits of requests and responses, enforced as they are sent and
+// received rather than once buffered.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrRequestBodyTooLarge is returned by Do for requests whose body exceeds
+	// Limits.MaxRequestBodySize. They are not sent.
+	ErrRequestBodyTooLarge = errors.New("request body too large")
+	// ErrResponseHeaderTooLarge is returned when the response headers exceed
+	// Limits.MaxResponseHeaderBytes. It is not retried.
+	ErrResponseHeaderTooLarge = errors.New("response headers too large")
+	// ErrResponseBodyTooLarge is returned by Do for responses whose Content-Length exceeds
+	// Limits.MaxResponseBodySize, and by the reads of response bodies past the limit.
+	ErrResponseBodyTooLarge = errors.New("response body too large")
+)
+
+// Limits are the size limits of requests and responses. Zero values mean no limit, but for
+// MaxBufferedBodySize.
+type Limits struct {
+	// MaxRequestBodySize bounds the size of the request bodies, as measured when requests are
+	// built. Requests whose body is larger fail with ErrRequestBodyTooLarge before being sent.
+	// Requests of unknown length, wrapping a streamed body with FromRequest, aren't checked.
+	MaxRequestBodySize int64
+	// MaxResponseHeaderBytes bounds the size of the response headers, which the transports of
+	// the client stop reading past it, failing with ErrResponseHeaderTooLarge. It doesn't apply
+	// to Options.HTTPClient or Options.SharedTransport, which are used as is.
+	MaxResponseHeaderBytes int64
+	// MaxResponseBodySize bounds the size of the response bodies returned by Do, decoded ones
+	// with AutoDecompress. Responses announcing a larger Content-Length fail right away with
+	// ErrResponseBodyTooLarge, and reads past the limit fail with it, or with
+	// ErrDecompressionBombDetected for the bodies decoded by AutoDecompress.
+	MaxResponseBodySize int64
+	// MaxBufferedBodySize bounds the size of the response bodies held whole in memory, by
+	// DoUntil and SingleFlight. Larger bodies fail with ErrResponseBodyTooLarge. Zero defaults
+	// to MaxResponseBodySize if set, 10MB otherwise.
+	MaxBufferedBodySize int64
+}
+
+// bufferedBodyLimit returns the size limit of the response bodies buffered whole.
+func (c *Client) bufferedBodyLimit() int64 {
+	switch {
+	case c.options.Limits.MaxBufferedBodySize > 0:
+		return c.options.Limits.MaxBufferedBodySize
+	case c.options.Limits.MaxResponseBodySize > 0:
+		return c.options.Limits.MaxResponseBodySize
+	default:
+		return defaultMaxBufferedBodySize
+	}
+}
+
+// checkRequestSize fails requests whose body exceeds Limits.MaxRequestBodySize.
+func (c *Client) checkRequestSize(req *Request) (err error) {
+	limit := c.options.Limits.MaxRequestBodySize
+
+	if limit > 0 && req.ContentLength > limit {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", ErrRequestBodyTooLarge, req.ContentLength, limit)
+	}
+
+	return
+}
+
+// limitResponse enforces Limits.MaxResponseBodySize on res: it fails responses announcing a
+// larger body, closing them, and limits the reads of the others.
+func (c *Client) limitResponse(res *http.Response) (*http.Response, error) {
+	limit := c.options.Limits.MaxResponseBodySize
+
+	if limit <= 0 || res.Body == nil {
+		return res, nil
+	}
+
+	if res.ContentLength > limit {
+		res.Body.Close()
+
+		return nil, fmt.Errorf("%w: %d bytes, the limit is %d", ErrResponseBodyTooLarge, res.ContentLength, limit)
+	}
+
+	res.Body = &limitedReadCloser{ReadCloser: res.Body, remaining: limit}
+
+	return res, nil
+}
+
+// limitedReadCloser fails reads past remaining bytes with ErrResponseBodyTooLarge.
+type limitedReadCloser struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReadCloser) Read(p []byte) (n int, err error) {
+	if l.remaining < 0 {
+		return 0, ErrResponseBodyTooLarge
+	}
+
+	// Read one byte more than allowed, to tell a body ending at the limit from a larger one
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+
+	n, err = l.ReadCloser.Read(p)
+
+	l.remaining -= int64(n)
+
+	if l.remaining < 0 {
+		return n - 1, ErrResponseBodyTooLarge
+	}
+
+	return
+}
+
+// responseHeaderError wraps err with ErrResponseHeaderTooLarge if the transport aborted on
+// headers exceeding its limit. net/http and x/net/http2 don't type these errors, which are
+// matched by messages.
+func responseHeaderError(err error) error {
+	if err == nil || errors.Is(err, ErrResponseHeaderTooLarge) {
+		return err
+	}
+
+	message := err.Error()
+
+	if strings.Contains(message, "server response headers exceeded") ||
+		strings.Contains(message, "http2: response header list larger than advertised limit") {
+		return fmt.Errorf("%w: %w", ErrResponseHeaderTooLarge, err)
+	}
+
+	return err
+}
+
+const defaultMaxBufferedBodySize = 10 << 20
@@ -0,0 +1,173 @@
+package hqgohttp
+
+// This file contains code for consuming response streams of plain text lines.
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ErrLineTooLong is returned by LineStream.Next when a line exceeds Options.MaxLineLength.
+var ErrLineTooLong = errors.New("line too long")
+
+// LineStream reads a response body one line at a time, only reading from the connection as
+// lines are asked for. It is not threadsafe.
+type LineStream struct {
+	// Response is the response the stream currently reads from, replaced on reconnection.
+	Response *http.Response
+
+	client     *Client
+	req        *Request
+	scanner    *bufio.Scanner
+	maxLength  int
+	reconnects int
+	stop       chan struct{}
+	stopOnce   sync.Once
+	release    func()
+	mutex      sync.Mutex
+	err        error
+}
+
+// StreamLines executes the request and returns a stream reading its body line by line. Lines
+// are limited to Options.MaxLineLength bytes. The body is closed once the stream ends, fails
+// or the request context is done, which also interrupts a pending Next.
+//
+// With Options.StreamReconnect, a stream broken by a read error is resumed by sending the
+// request again, up to RetryMax times in a row, the count being reset by every line read.
+// Lines sent by the server in between are lost, and a body ending normally isn't resumed.
+func (c *Client) StreamLines(req *Request) (stream *LineStream, err error) {
+	release, err := c.acquireGlobalSlot(req.Context())
+	if err != nil {
+		return
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		release()
+
+		return
+	}
+
+	stream = &LineStream{
+		client:    c,
+		req:       req,
+		maxLength: c.options.MaxLineLength,
+		stop:      make(chan struct{}),
+		release:   release,
+	}
+
+	if stream.maxLength <= 0 {
+		stream.maxLength = defaultMaxLineLength
+	}
+
+	stream.reset(res)
+
+	go func() {
+		select {
+		case <-req.Context().Done():
+			stream.closeBody()
+		case <-stream.stop:
+		}
+
+		stream.release()
+	}()
+
+	return
+}
+
+// Next returns the next line of the stream, without its line ending ("\n" or "\r\n"). It
+// returns io.EOF when the stream is exhausted, and ErrLineTooLong for lines longer than the
+// limit. After the first error, every call returns that same error.
+func (s *LineStream) Next() (line string, err error) {
+	for s.err == nil {
+		if s.scanner.Scan() {
+			s.reconnects = 0
+
+			return s.scanner.Text(), nil
+		}
+
+		err = s.scanner.Err()
+
+		switch ctxErr := s.req.Context().Err(); {
+		case ctxErr != nil:
+			err = ctxErr
+		case err == nil:
+			err = io.EOF
+		case errors.Is(err, bufio.ErrTooLong):
+			err = ErrLineTooLong
+		case s.reconnect():
+			continue
+		}
+
+		s.err = err
+
+		s.Close()
+	}
+
+	return "", s.err
+}
+
+// reconnect sends the request again after a read error, if StreamReconnect allows it.
+func (s *LineStream) reconnect() bool {
+	if !s.client.options.StreamReconnect || s.reconnects >= s.client.getRetryMax(s.req) {
+		return false
+	}
+
+	s.reconnects++
+
+	s.closeBody()
+
+	res, err := s.client.Do(s.req.Clone(s.req.Context()))
+	if err != nil {
+		return false
+	}
+
+	s.reset(res)
+
+	return true
+}
+
+// reset makes the stream read from res.
+func (s *LineStream) reset(res *http.Response) {
+	s.mutex.Lock()
+	s.Response = res
+	s.mutex.Unlock()
+
+	size := defaultBufferSize
+
+	if s.maxLength < size {
+		size = s.maxLength
+	}
+
+	s.scanner = bufio.NewScanner(res.Body)
+
+	s.scanner.Buffer(make([]byte, 0, size), s.maxLength)
+}
+
+// closeBody closes the body of the current response, interrupting a pending read.
+func (s *LineStream) closeBody() (err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.Response.Body.Close()
+}
+
+// Close closes the underlying response body. It is safe to call multiple times.
+func (s *LineStream) Close() error {
+	if s.err == nil {
+		s.err = io.EOF
+	}
+
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+
+	s.release()
+
+	return s.closeBody()
+}
+
+const defaultMaxLineLength = 64 * 1024
@@ -0,0 +1,126 @@
+package hqgohttp
+
+// This file contains code for extracting the links of HTML responses, for crawlers.
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"golang.org/x/net/html"
+)
+
+// ExtractLinks returns the absolute http(s) URLs of the response's Link headers and of the
+// href and src attributes of its HTML body, in order of appearance and without duplicates.
+// Relative links are resolved against the document's <base href>, if any, then base, which
+// defaults to the URL of the request. Only the first RespReadLimit bytes of the body are
+// parsed, and the body is restored so the caller can still read it whole.
+func (c *Client) ExtractLinks(resp *http.Response, base *url.URL) (links []*url.URL, err error) {
+	if base == nil && resp.Request != nil {
+		base = resp.Request.URL
+	}
+
+	if base == nil {
+		base = &url.URL{}
+	}
+
+	seen := map[string]bool{}
+
+	add := func(from *url.URL, ref string) {
+		ref = strings.TrimSpace(ref)
+
+		if ref == "" {
+			return
+		}
+
+		parsed, err := from.Parse(ref)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return
+		}
+
+		if seen[parsed.String()] {
+			return
+		}
+
+		seen[parsed.String()] = true
+
+		links = append(links, parsed)
+	}
+
+	for _, value := range resp.Header.Values(headers.Link) {
+		for _, ref := range parseLinkHeader(value) {
+			add(base, ref)
+		}
+	}
+
+	limit := c.options.RespReadLimit
+
+	if limit <= 0 {
+		limit = defaultSnapshotLimit
+	}
+
+	snapshot, err := c.snapshot(resp.Body, limit)
+
+	resp.Body = &struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(snapshot), resp.Body),
+		Closer: resp.Body,
+	}
+
+	if err != nil {
+		return
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(snapshot))
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// io.EOF, or a truncated snapshot, ends the document
+			return links, nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+
+			for _, attr := range token.Attr {
+				if attr.Key != "href" && attr.Key != "src" {
+					continue
+				}
+
+				if token.Data == "base" && attr.Key == "href" {
+					if parsed, err := base.Parse(strings.TrimSpace(attr.Val)); err == nil {
+						base = parsed
+					}
+
+					continue
+				}
+
+				add(base, attr.Val)
+			}
+		}
+	}
+}
+
+// parseLinkHeader returns the URI references of a Link header value, e.g.
+// `<https://example.com/?page=2>; rel="next", </?page=5>; rel="last"`.
+func parseLinkHeader(value string) (refs []string) {
+	for {
+		start := strings.IndexByte(value, '<')
+		if start < 0 {
+			return
+		}
+
+		end := strings.IndexByte(value[start:], '>')
+		if end < 0 {
+			return
+		}
+
+		refs = append(refs, value[start+1:start+end])
+
+		value = value[start+end+1:]
+	}
+}
@@ -0,0 +1,102 @@
+package hqgohttp
+
+// This file contains the binding of outgoing connections to a local address or network interface.
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrInvalidLocalAddr is returned by New when Options.LocalAddr or Options.Interface can't be
+// bound to.
+var ErrInvalidLocalAddr = errors.New("invalid local address")
+
+// localAddr returns the address to bind outgoing connections to, if any: Options.LocalAddr, or
+// an address of Options.Interface, of the IP version of the options, IPv4 ones being preferred
+// for DualStack. Connections bound to an address only reach hosts over its IP version.
+func localAddr(options *Options) (addr *net.TCPAddr, err error) {
+	switch {
+	case options.LocalAddr != nil:
+		return checkLocalAddr(options.LocalAddr)
+	case options.Interface != "":
+		return interfaceAddr(options.Interface, options.IPVersion)
+	default:
+		return nil, nil
+	}
+}
+
+// checkLocalAddr checks local is a TCP address assigned to the host, or an unspecified one.
+func checkLocalAddr(local net.Addr) (addr *net.TCPAddr, err error) {
+	addr, ok := local.(*net.TCPAddr)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is a %T, not a *net.TCPAddr", ErrInvalidLocalAddr, local, local)
+	}
+
+	if addr.IP == nil || addr.IP.IsUnspecified() {
+		return
+	}
+
+	assigned, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("%w: listing the addresses of the host: %w", ErrInvalidLocalAddr, err)
+	}
+
+	for _, a := range assigned {
+		if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.Equal(addr.IP) {
+			return
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s is not assigned to any interface of the host", ErrInvalidLocalAddr, addr.IP)
+}
+
+// interfaceAddr returns an address of the interface named name, of the IP version.
+func interfaceAddr(name string, version IPVersion) (addr *net.TCPAddr, err error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: interface %s: %w", ErrInvalidLocalAddr, name, err)
+	}
+
+	if iface.Flags&net.FlagUp == 0 {
+		return nil, fmt.Errorf("%w: interface %s is down", ErrInvalidLocalAddr, name)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("%w: listing the addresses of interface %s: %w", ErrInvalidLocalAddr, name, err)
+	}
+
+	var v4, v6 net.IP
+
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case ipNet.IP.To4() != nil:
+			if v4 == nil {
+				v4 = ipNet.IP
+			}
+		// Link-local IPv6 addresses need a zone, and only reach the link
+		case !ipNet.IP.IsLinkLocalUnicast():
+			if v6 == nil {
+				v6 = ipNet.IP
+			}
+		}
+	}
+
+	ip, family := v4, "IPv4"
+
+	if version == IPv6Only || (version == DualStack && v4 == nil) {
+		ip, family = v6, "IPv6"
+	}
+
+	if ip == nil {
+		return nil, fmt.Errorf("%w: interface %s has no usable %s address", ErrInvalidLocalAddr, name, family)
+	}
+
+	return &net.TCPAddr{IP: ip}, nil
+}
@@ -0,0 +1,121 @@
+package hqgohttp
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestLocalAddr(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	defer server.Close()
+
+	// Bind to a port known free, so that the server sees the connection come from it
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local, _ := listener.Addr().(*net.TCPAddr)
+
+	listener.Close()
+
+	var loopback string
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			loopback = iface.Name
+
+			break
+		}
+	}
+
+	tests := []struct {
+		name    string
+		options func(o *Options)
+		want    string
+	}{
+		{"address", func(o *Options) { o.LocalAddr = local }, local.String()},
+		{"interface", func(o *Options) { o.Interface = loopback }, ""},
+	}
+
+	for _, tt := range tests {
+		if tt.name == "interface" && loopback == "" {
+			t.Log("no loopback interface up, skipping the interface binding")
+
+			continue
+		}
+
+		options := *DefaultOptionsSingle
+		options.IPVersion = IPv4Only
+
+		tt.options(&options)
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		req, err := NewRequest(methods.Get, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+
+		res.Body.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		host, _, _ := net.SplitHostPort(string(body))
+
+		if host != "127.0.0.1" || (tt.want != "" && string(body) != tt.want) {
+			t.Errorf("%s: got connections from %s, want %s", tt.name, body, tt.want)
+		}
+	}
+}
+
+func TestInvalidLocalAddr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options func(o *Options)
+	}{
+		// TEST-NET-1, reserved for documentation
+		{"unassigned address", func(o *Options) { o.LocalAddr = &net.TCPAddr{IP: net.ParseIP("192.0.2.1")} }},
+		{"not TCP", func(o *Options) { o.LocalAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)} }},
+		{"unknown interface", func(o *Options) { o.Interface = "hqgohttp-none0" }},
+	}
+
+	for _, tt := range tests {
+		options := *DefaultOptionsSingle
+
+		tt.options(&options)
+
+		if _, err := New(&options); !errors.Is(err, ErrInvalidLocalAddr) {
+			t.Errorf("%s: got %v, want ErrInvalidLocalAddr", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package hqgohttp
+
+// This file contains code for long-polling endpoints, holding the request until data is
+// available and answering with it, the next request being sent right after.
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// LongPoll sends the request over and over until its context is done, passing every response
+// but 204 No Content, which means no data, to handler. Each response body is drained and closed
+// once handler returns. Requests start at least Options.LongPollInterval apart, so an endpoint
+// answering at once doesn't turn the loop into a busy one.
+//
+// Failed requests are retried following the retry policy of the client. A request failing
+// nonetheless doesn't stop the loop, the next one being sent at least LongPollInterval, and
+// RetryWaitMin, later. LongPoll returns the error of handler, or the context error once it's
+// done.
+func (c *Client) LongPoll(req *Request, handler func(*http.Response) error) (err error) {
+	ctx := req.Context()
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		start := c.clock.Now()
+
+		var res *http.Response
+
+		if res, err = c.Do(req.Clone(ctx)); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			if err = c.waitLongPoll(ctx, c.clock.Now(), c.options.RetryWaitMin); err != nil {
+				return
+			}
+
+			continue
+		}
+
+		if res.StatusCode != status.NoContent {
+			err = handler(res)
+		}
+
+		c.drainBody(req, res)
+
+		if err != nil {
+			return
+		}
+
+		if err = c.waitLongPoll(ctx, start, 0); err != nil {
+			return
+		}
+	}
+}
+
+// waitLongPoll waits until LongPollInterval, or minWait if longer, has passed since start, or
+// the context is done.
+func (c *Client) waitLongPoll(ctx context.Context, start time.Time, minWait time.Duration) (err error) {
+	interval := c.options.LongPollInterval
+
+	if interval < minWait {
+		interval = minWait
+	}
+
+	wait := interval - c.clock.Now().Sub(start)
+
+	if wait <= 0 {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-c.clock.After(wait):
+	}
+
+	return
+}
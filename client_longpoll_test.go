@@ -0,0 +1,75 @@
+package hqgohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestLongPollKeepsPollingOnErrors(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		switch atomic.AddInt32(&hits, 1) {
+		case 1, 2:
+			panic(http.ErrAbortHandler)
+		case 3:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte("data"))
+		}
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryWaitMin = 10 * time.Millisecond
+	options.LongPollInterval = 20 * time.Millisecond
+	options.CheckRetry = func(context.Context, *http.Response, error) (bool, error) {
+		return false, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := NewRequestWithContext(ctx, methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var handled int
+
+	start := time.Now()
+
+	errStop := errors.New("stop")
+
+	err = client.LongPoll(req, func(*http.Response) error {
+		handled++
+
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("got %v, want the handler error", err)
+	}
+
+	if handled != 1 || atomic.LoadInt32(&hits) != 4 {
+		t.Fatalf("got %d responses handled out of %d requests, want 1 out of 4", handled, hits)
+	}
+
+	// The failed requests are followed by a LongPollInterval wait each
+	if elapsed := time.Since(start); elapsed < 2*options.LongPollInterval {
+		t.Fatalf("polled for %s, want the failures spaced out", elapsed)
+	}
+}
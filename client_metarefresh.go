@@ -0,0 +1,205 @@
+package hqgohttp
+
+// This file contains the following of HTML meta refresh redirects, which legacy sites use
+// instead of 3xx responses.
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+	"golang.org/x/net/html"
+)
+
+// followMetaRefresh follows the meta refresh redirects of res, a response to req, when
+// Options.FollowMetaRefresh is set. A refresh is followed, with a GET carrying the headers of
+// req, after waiting its delay, provided it points to another URL and its delay doesn't exceed
+// Options.MetaRefreshMaxDelay. The URLs followed are recorded in req's Metrics.MetaRefreshes.
+// Past 10 hops, or on a loop, the last response is returned as is.
+func (c *Client) followMetaRefresh(req *Request, res *http.Response) (*http.Response, error) {
+	maxDelay := c.options.MetaRefreshMaxDelay
+
+	if maxDelay == 0 {
+		maxDelay = defaultMetaRefreshMaxDelay
+	}
+
+	seen := map[string]bool{req.URL.String(): true}
+
+	for hops := 0; hops < maxRedirects; hops++ {
+		target, delay, ok := c.metaRefresh(res)
+		if !ok || delay > maxDelay || seen[target.String()] {
+			return res, nil
+		}
+
+		seen[target.String()] = true
+
+		select {
+		case <-req.Context().Done():
+			res.Body.Close()
+
+			return nil, req.Context().Err()
+		case <-c.clock.After(delay):
+		}
+
+		next, err := NewRequestWithContext(req.Context(), methods.Get, target.String(), nil)
+		if err != nil {
+			res.Body.Close()
+
+			return nil, err
+		}
+
+		next.Header = req.Header.Clone()
+
+		next.Header.Del(headers.ContentType)
+		next.Header.Del(headers.ContentLength)
+
+		c.drainBody(req, res)
+
+		req.Metrics.MetaRefreshes = append(req.Metrics.MetaRefreshes, target.String())
+
+		if res, err = c.dispatch(next); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// metaRefresh returns the target and delay of the meta refresh redirect of res, an HTML 200
+// response, if any. Only the head of the document, within the first RespReadLimit bytes, is
+// parsed, and the bytes read are put back in front of the body.
+func (c *Client) metaRefresh(res *http.Response) (target *url.URL, delay time.Duration, ok bool) {
+	if res.StatusCode != status.OK || res.Body == nil || !isHTML(res.Header.Get(headers.ContentType)) {
+		return
+	}
+
+	limit := c.options.RespReadLimit
+
+	if limit <= 0 {
+		limit = defaultSnapshotLimit
+	}
+
+	var consumed bytes.Buffer
+
+	tokenizer := html.NewTokenizer(io.TeeReader(io.LimitReader(res.Body, limit), &consumed))
+
+	defer func() {
+		res.Body = &struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: io.MultiReader(bytes.NewReader(consumed.Bytes()), res.Body),
+			Closer: res.Body,
+		}
+	}()
+
+	var content string
+
+	for content == "" {
+		tokenType := tokenizer.Next()
+
+		if tokenType == html.ErrorToken {
+			return
+		}
+
+		token := tokenizer.Token()
+
+		switch {
+		case tokenType == html.EndTagToken && token.Data == "head":
+			return
+		case tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken:
+			continue
+		case token.Data == "body":
+			return
+		case token.Data != "meta":
+			continue
+		}
+
+		var refresh bool
+
+		var value string
+
+		for _, attr := range token.Attr {
+			switch strings.ToLower(attr.Key) {
+			case "http-equiv":
+				refresh = strings.EqualFold(strings.TrimSpace(attr.Val), "refresh")
+			case "content":
+				value = attr.Val
+			}
+		}
+
+		if refresh {
+			content = value
+		}
+	}
+
+	delay, ref, ok := parseMetaRefresh(content)
+	if !ok {
+		return
+	}
+
+	target, ok = resolveReference(res, ref)
+
+	return
+}
+
+// parseMetaRefresh parses the content of a meta refresh, e.g. "5; url=https://example.com/".
+// ok is false when it has no URL, the page then only reloads itself.
+func parseMetaRefresh(content string) (delay time.Duration, ref string, ok bool) {
+	content = strings.TrimSpace(content)
+
+	end := strings.IndexAny(content, ";,")
+
+	if end < 0 {
+		return
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(content[:end]), 64)
+	if err != nil || seconds < 0 {
+		return
+	}
+
+	ref = strings.TrimSpace(content[end+1:])
+
+	if len(ref) >= 3 && strings.EqualFold(ref[:3], "url") {
+		if rest := strings.TrimSpace(ref[3:]); strings.HasPrefix(rest, "=") {
+			ref = strings.TrimSpace(rest[1:])
+		}
+	}
+
+	ref = strings.Trim(ref, `"'`)
+
+	return time.Duration(seconds * float64(time.Second)), ref, ref != ""
+}
+
+// resolveReference resolves ref against the URL of the request of res, to an http(s) URL.
+func resolveReference(res *http.Response, ref string) (target *url.URL, ok bool) {
+	base := &url.URL{}
+
+	if res.Request != nil {
+		base = res.Request.URL
+	}
+
+	target, err := base.Parse(ref)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return nil, false
+	}
+
+	return target, true
+}
+
+// isHTML reports whether contentType is the media type of an HTML document.
+func isHTML(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+
+	return strings.HasPrefix(contentType, "text/html") || strings.HasPrefix(contentType, "application/xhtml+xml")
+}
+
+const defaultMetaRefreshMaxDelay = 5 * time.Second
@@ -0,0 +1,139 @@
+package hqgohttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestBytesMetrics(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(strings.Repeat("e", 100)))
+
+			return
+		}
+
+		w.Write([]byte(strings.Repeat("o", 500)))
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 1
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+	options.CheckRetry = func(_ context.Context, res *http.Response, err error) (bool, error) {
+		return err != nil || res.StatusCode >= 500, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Post, server.URL, bytes.NewReader(make([]byte, 1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = io.Copy(io.Discard, res.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	// Both attempts send the same request line, headers and body
+	if want := 2 * requestSize(req.Request); req.Metrics.BytesSent != want {
+		t.Fatalf("got %d bytes sent, want %d", req.Metrics.BytesSent, want)
+	}
+
+	// The drained error body and the body read by the caller
+	if req.Metrics.BytesReceived != 600 {
+		t.Fatalf("got %d bytes received, want 600", req.Metrics.BytesReceived)
+	}
+}
+
+func TestNegotiatedProtocol(t *testing.T) {
+	t.Parallel()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	h2 := httptest.NewUnstartedServer(handler)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+
+	defer h2.Close()
+
+	h1 := httptest.NewTLSServer(handler)
+	defer h1.Close()
+
+	plaintext := httptest.NewServer(handler)
+	defer plaintext.Close()
+
+	tests := []struct {
+		name       string
+		URL        string
+		forceHTTP2 bool
+		want       string
+		wantProto  string
+	}{
+		{"h2", h2.URL, false, "h2", "HTTP/2.0"},
+		{"native h2", h2.URL, true, "h2", "HTTP/2.0"},
+		{"http/1.1", h1.URL, false, "http/1.1", "HTTP/1.1"},
+		{"plaintext", plaintext.URL, false, "", "HTTP/1.1"},
+	}
+
+	for _, tt := range tests {
+		options := *DefaultOptionsSingle
+		options.ForceHTTP2 = tt.forceHTTP2
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := NewRequest(methods.Get, tt.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(req.SkipTLSVerify())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+
+		res.Body.Close()
+
+		if err != nil || string(body) != tt.wantProto {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, body, err, tt.wantProto)
+		}
+
+		if req.Metrics.NegotiatedProtocol != tt.want {
+			t.Errorf("%s: got protocol %q, want %q", tt.name, req.Metrics.NegotiatedProtocol, tt.want)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package hqgohttp
+
+// This file contains code for consuming newline-delimited JSON (NDJSON) response streams.
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// NDJSONStream decodes a newline-delimited JSON response body one object at a time.
+// It is not threadsafe.
+type NDJSONStream struct {
+	// Response is the response the stream reads from.
+	Response *http.Response
+
+	ctx    context.Context //nolint:containedctx // The stream is bound to the request lifetime
+	reader *bufio.Reader
+	err    error
+}
+
+// DoNDJSON executes the request and returns a stream decoding its body as NDJSON.
+// The body is closed once the stream ends, fails or the request context is done.
+func (c *Client) DoNDJSON(req *Request) (stream *NDJSONStream, err error) {
+	release, err := c.acquireGlobalSlot(req.Context())
+	if err != nil {
+		return
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		release()
+
+		return
+	}
+
+	// The stream holds its slot until the body is closed
+	res.Body = &releasingReadCloser{ReadCloser: res.Body, release: release}
+
+	stream = &NDJSONStream{
+		Response: res,
+		ctx:      req.Context(),
+		reader:   bufio.NewReader(res.Body),
+	}
+
+	return
+}
+
+// Next decodes the next JSON object of the stream into v. Blank lines are skipped and
+// lines are read whole, regardless of their size. It returns io.EOF when the stream
+// is exhausted. After the first error, every call returns that same error.
+func (s *NDJSONStream) Next(v interface{}) (err error) {
+	if s.err != nil {
+		return s.err
+	}
+
+	defer func() {
+		if err != nil {
+			s.Close()
+		}
+	}()
+
+	for {
+		if err = s.ctx.Err(); err != nil {
+			s.err = err
+
+			return
+		}
+
+		var line []byte
+
+		line, err = s.reader.ReadBytes('\n')
+
+		line = bytes.TrimSpace(line)
+
+		// A last line without a trailing newline is still a complete object.
+		if len(line) > 0 {
+			if decodeErr := json.Unmarshal(line, v); decodeErr != nil {
+				s.err = decodeErr
+
+				return decodeErr
+			}
+
+			if errors.Is(err, io.EOF) {
+				// Report the object now, EOF on the next call.
+				s.err = io.EOF
+				err = nil
+
+				s.Close()
+			}
+
+			return
+		}
+
+		if err != nil {
+			s.err = err
+
+			return
+		}
+	}
+}
+
+// Close closes the underlying response body. It is safe to call multiple times.
+func (s *NDJSONStream) Close() error {
+	if s.err == nil {
+		s.err = io.EOF
+	}
+
+	return s.Response.Body.Close()
+}
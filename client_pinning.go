@@ -0,0 +1,89 @@
+package hqgohttp
+
+// This file contains the certificate pinning of TLS connections, trusting servers by the
+// public keys they present rather than by the system CA store.
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrCertPinMismatch is returned when a server presents no certificate matching the pinned
+// public keys, or a chain that doesn't verify up to the pinned certificate. It is not retried.
+var ErrCertPinMismatch = errors.New("certificate pin mismatch")
+
+// PublicKeySHA256 returns the pin of a certificate: the base64 encoded SHA-256 digest of
+// its DER encoded SubjectPublicKeyInfo, as in Options.PinnedPublicKeySHA256.
+func PublicKeySHA256(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// pinTransport makes the transport trust only the servers presenting a certificate whose public
+// key is pinned. The system CA store is bypassed: the presented chain must verify, for the server
+// name, up to a pinned certificate, be it the leaf itself (self-signed) or one of its issuers.
+func pinTransport(transport *http.Transport, pins []string) {
+	pinned := make(map[string]bool, len(pins))
+
+	for _, pin := range pins {
+		pinned[pin] = true
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+
+	// Pins replace the CA store, the chain is verified by VerifyConnection instead
+	config.InsecureSkipVerify = true
+	config.VerifyConnection = func(state tls.ConnectionState) error {
+		return verifyPins(state, pinned)
+	}
+
+	transport.TLSClientConfig = config
+}
+
+func verifyPins(state tls.ConnectionState, pinned map[string]bool) (err error) {
+	if len(state.PeerCertificates) == 0 {
+		return fmt.Errorf("%w: no certificate presented", ErrCertPinMismatch)
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+
+	matched := false
+
+	for _, cert := range state.PeerCertificates {
+		if pinned[PublicKeySHA256(cert)] {
+			roots.AddCert(cert)
+
+			matched = true
+
+			continue
+		}
+
+		intermediates.AddCert(cert)
+	}
+
+	if !matched {
+		return fmt.Errorf("%w: no pinned public key in the chain presented by %s", ErrCertPinMismatch, state.ServerName)
+	}
+
+	_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCertPinMismatch, err)
+	}
+
+	return
+}
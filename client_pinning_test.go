@@ -0,0 +1,73 @@
+package hqgohttp
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestCertificatePinning(t *testing.T) {
+	t.Parallel()
+
+	// The certificate of httptest is self-signed, unknown to the system CA store
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("pinned"))
+	}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+
+	defer server.Close()
+
+	otherKey := sha256.Sum256([]byte("another key"))
+
+	tests := []struct {
+		name string
+		pin  string
+		err  error
+	}{
+		{"pinned key", PublicKeySHA256(server.Certificate()), nil},
+		{"other key", base64.StdEncoding.EncodeToString(otherKey[:]), ErrCertPinMismatch},
+	}
+
+	for _, tt := range tests {
+		options := *DefaultOptionsSingle
+		options.PinnedPublicKeySHA256 = []string{tt.pin}
+		options.RetryWaitMin = time.Second
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := NewRequest(methods.Get, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start := time.Now()
+
+		res, err := client.Do(req)
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("%s: got %v, want %v", tt.name, err, tt.err)
+		}
+
+		if err != nil {
+			// The default retry policy doesn't retry mismatches
+			if elapsed := time.Since(start); elapsed >= options.RetryWaitMin {
+				t.Fatalf("%s: failed after %s, want no retries", tt.name, elapsed)
+			}
+
+			continue
+		}
+
+		res.Body.Close()
+	}
+}
@@ -0,0 +1,158 @@
+package hqgohttp
+
+// This file contains code for checking hosts reachability with a bare TCP dial, without a full HTTP request.
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// Probe checks whether a TCP connection to host:port can be established, reusing the
+// client's dialer, proxy (tunneling through HTTP CONNECT) and timeout, so the probe
+// reflects the connectivity of real requests. It returns the time taken to connect,
+// which can be used to prioritize hosts. The connection is closed right away.
+func (c *Client) Probe(ctx context.Context, host string, port int) (reachable bool, latency time.Duration, err error) {
+	if c.HTTPClient.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.HTTPClient.Timeout)
+
+		defer cancel()
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	dial, proxy := c.dialConfig()
+
+	var proxyURL *url.URL
+
+	if proxy != nil {
+		target := &http.Request{URL: &url.URL{Scheme: "https", Host: address}, Header: http.Header{}}
+
+		if proxyURL, err = proxy(target); err != nil {
+			return
+		}
+	}
+
+	start := c.clock.Now()
+
+	var conn net.Conn
+
+	if proxyURL != nil {
+		conn, err = dialThroughProxy(ctx, dial, proxyURL, address)
+	} else {
+		conn, err = dial(ctx, "tcp", address)
+	}
+
+	latency = c.clock.Now().Sub(start)
+
+	if err != nil {
+		return
+	}
+
+	conn.Close()
+
+	reachable = true
+
+	return
+}
+
+// dialConfig returns the dial and proxy functions of the client's transport,
+// falling back to the ones built from the client's options.
+func (c *Client) dialConfig() (dial func(ctx context.Context, network, addr string) (net.Conn, error), proxy func(*http.Request) (*url.URL, error)) {
+	if transport, ok := c.HTTPClient.Transport.(*http.Transport); ok && transport.DialContext != nil {
+		return transport.DialContext, transport.Proxy
+	}
+
+	return dialContext(&c.options), nil
+}
+
+// dialThroughProxy opens a tunnel to address through an HTTP(S) proxy with the CONNECT method.
+func dialThroughProxy(ctx context.Context, dial func(ctx context.Context, network, addr string) (net.Conn, error), proxyURL *url.URL, address string) (conn net.Conn, err error) {
+	if proxyURL.Scheme != "http" && proxyURL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+
+	proxyAddress := proxyURL.Host
+
+	if proxyURL.Port() == "" {
+		port := "80"
+
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+
+		proxyAddress = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	if conn, err = dial(ctx, "tcp", proxyAddress); err != nil {
+		return
+	}
+
+	if proxyURL.Scheme == "https" {
+		TLSConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname(), MinVersion: tls.VersionTLS12})
+
+		if err = TLSConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+
+			return nil, err
+		}
+
+		conn = TLSConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	connect := &http.Request{
+		Method: methods.Connect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+
+		connect.SetBasicAuth(proxyURL.User.Username(), password)
+		connect.Header.Set(headers.ProxyAuthorization, connect.Header.Get(headers.Authorization))
+		connect.Header.Del(headers.Authorization)
+	}
+
+	if err = connect.Write(conn); err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	res, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	res.Body.Close()
+
+	if res.StatusCode != status.OK {
+		conn.Close()
+
+		return nil, fmt.Errorf("proxy CONNECT to %s failed: %s", address, res.Status)
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
@@ -0,0 +1,184 @@
+package hqgohttp
+
+// This file contains the parsing of the rate limit headers of responses, in their competing
+// conventions, and the pacing of requests to the hosts whose rate limit is exhausted.
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/headers"
+)
+
+// RateLimit is the rate limit state a server reported in the headers of a response.
+type RateLimit struct {
+	// Limit is the number of requests allowed per window, -1 if not reported
+	Limit int64
+	// Remaining is the number of requests left in the window, -1 if not reported
+	Remaining int64
+	// ResetAt is when the window resets, zero if not reported
+	ResetAt time.Time
+}
+
+// ParseRateLimit returns the rate limit state of the headers of a response, received at now. ok
+// is false when they report none. The following conventions are supported:
+//
+//   - X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset, as used by GitHub, and
+//     X-Rate-Limit-Limit, X-Rate-Limit-Remaining and X-Rate-Limit-Reset, as used by Twitter,
+//     whose reset is a Unix timestamp in seconds.
+//   - RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset, of the early IETF drafts, whose
+//     reset is a number of seconds.
+//   - RateLimit: limit=100, remaining=50, reset=30 and RateLimit: "default";r=50;t=30, of the
+//     later IETF drafts, with the limit of the latter in RateLimit-Policy: "default";q=100;w=60.
+//
+// Resets are taken as a number of seconds below 10^9, and as a Unix timestamp from there.
+func ParseRateLimit(header http.Header, now time.Time) (limit RateLimit, ok bool) {
+	limit = RateLimit{Limit: -1, Remaining: -1}
+
+	set := func(value string, field *int64) {
+		if n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil && n >= 0 {
+			*field = n
+			ok = true
+		}
+	}
+
+	reset := func(value string) {
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || n < 0 {
+			return
+		}
+
+		if n < unixTimestampThreshold {
+			limit.ResetAt = now.Add(time.Duration(n * float64(time.Second)))
+		} else {
+			limit.ResetAt = time.Unix(int64(n), 0)
+		}
+
+		ok = true
+	}
+
+	for _, family := range rateLimitHeaders {
+		if value := header.Get(family[0]); value != "" {
+			set(value, &limit.Limit)
+		}
+
+		if value := header.Get(family[1]); value != "" {
+			set(value, &limit.Remaining)
+		}
+
+		if value := header.Get(family[2]); value != "" {
+			reset(value)
+		}
+
+		if ok {
+			return
+		}
+	}
+
+	// Structured fields, e.g. limit=100, remaining=50, reset=30 or "default";r=50;t=30
+	for _, item := range strings.FieldsFunc(header.Get(headers.RateLimit), func(r rune) bool {
+		return r == ',' || r == ';'
+	}) {
+		key, value, found := strings.Cut(strings.TrimSpace(item), "=")
+		if !found {
+			continue
+		}
+
+		switch strings.ToLower(key) {
+		case "limit":
+			set(value, &limit.Limit)
+		case "remaining", "r":
+			set(value, &limit.Remaining)
+		case "reset", "t":
+			reset(value)
+		}
+	}
+
+	for _, item := range strings.Split(header.Get(headers.RateLimitPolicy), ";") {
+		if key, value, found := strings.Cut(strings.TrimSpace(item), "="); found && key == "q" && limit.Limit < 0 {
+			set(value, &limit.Limit)
+		}
+	}
+
+	return
+}
+
+// rateLimits holds when the rate limit of hosts that exhausted it resets.
+type rateLimits struct {
+	mutex  sync.Mutex
+	resets map[string]time.Time
+}
+
+// recordRateLimit stores the rate limit state of res in the request's Metrics.RateLimit and,
+// with AdaptToRateLimit, remembers when the exhausted rate limit of the host resets.
+func (c *Client) recordRateLimit(req *Request, res *http.Response) {
+	if res == nil {
+		return
+	}
+
+	limit, ok := ParseRateLimit(res.Header, c.clock.Now())
+	if !ok {
+		return
+	}
+
+	req.Metrics.RateLimit = &limit
+
+	if !c.options.AdaptToRateLimit || limit.Remaining != 0 || limit.ResetAt.IsZero() {
+		return
+	}
+
+	c.rateLimits.mutex.Lock()
+	defer c.rateLimits.mutex.Unlock()
+
+	if c.rateLimits.resets == nil {
+		c.rateLimits.resets = map[string]time.Time{}
+	}
+
+	c.rateLimits.resets[req.URL.Host] = limit.ResetAt
+}
+
+// waitRateLimit waits, with AdaptToRateLimit, until the exhausted rate limit of the host of the
+// request resets, or until the request context is done.
+func (c *Client) waitRateLimit(req *Request) (err error) {
+	if !c.options.AdaptToRateLimit {
+		return
+	}
+
+	c.rateLimits.mutex.Lock()
+
+	resetAt, ok := c.rateLimits.resets[req.URL.Host]
+
+	now := c.clock.Now()
+
+	if ok && !resetAt.After(now) {
+		delete(c.rateLimits.resets, req.URL.Host)
+	}
+
+	c.rateLimits.mutex.Unlock()
+
+	if !ok || !resetAt.After(now) {
+		return
+	}
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-c.clock.After(resetAt.Sub(now)):
+	}
+
+	return
+}
+
+// rateLimitHeaders are the limit, remaining and reset headers of the rate limit conventions.
+var rateLimitHeaders = [][3]string{
+	{headers.XRatelimitLimit, headers.XRatelimitRemaining, headers.XRatelimitReset},
+	{headers.XRateLimitLimit, headers.XRateLimitRemaining, headers.XRateLimitReset},
+	{headers.RateLimitLimit, headers.RateLimitRemaining, headers.RateLimitReset},
+}
+
+// unixTimestampThreshold tells the resets given in seconds from the ones given as Unix
+// timestamps, which are past it since 2001.
+const unixTimestampThreshold = 1e9
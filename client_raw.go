@@ -0,0 +1,170 @@
+package hqgohttp
+
+// This file contains code for sending raw, possibly non-conformant, requests for security research.
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DoRaw writes raw to a new connection to host as is and parses what comes back as an
+// HTTP response. It intentionally bypasses the request normalization of net/http, so
+// requests the standard library refuses to send (e.g. with both Content-Length and
+// Transfer-Encoding, or a custom request line) can be sent. It is meant for security
+// research and bypasses retries, hooks and default headers as well.
+//
+// host is either "host:port", dialed over plain TCP, or a URL such as "https://host[:port]"
+// whose scheme selects TLS. The connection honors the client's dialer, proxy (tunneling
+// through HTTP CONNECT), TLS configuration and timeout. It is closed with the response body.
+func (c *Client) DoRaw(host string, raw []byte) (res *http.Response, err error) {
+	address, useTLS, err := rawAddress(host)
+	if err != nil {
+		return
+	}
+
+	ctx := context.Background()
+
+	var cancel context.CancelFunc = func() {}
+
+	if c.HTTPClient.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.HTTPClient.Timeout)
+	}
+
+	conn, err := c.dialRaw(ctx, address, useTLS)
+	if err != nil {
+		cancel()
+
+		return
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if _, err = conn.Write(raw); err != nil {
+		conn.Close()
+		cancel()
+
+		return nil, err
+	}
+
+	if res, err = http.ReadResponse(bufio.NewReader(conn), nil); err != nil {
+		conn.Close()
+		cancel()
+
+		return nil, err
+	}
+
+	rawBody := res.Body
+
+	res.Body = &struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: rawBody,
+		Closer: closerFunc(func() error {
+			rawBody.Close()
+			cancel()
+
+			return conn.Close()
+		}),
+	}
+
+	return
+}
+
+// dialRaw opens a connection to address with the client's dialer and proxy, over TLS if asked.
+func (c *Client) dialRaw(ctx context.Context, address string, useTLS bool) (conn net.Conn, err error) {
+	dial, proxy := c.dialConfig()
+
+	scheme := "http"
+
+	if useTLS {
+		scheme = "https"
+	}
+
+	var proxyURL *url.URL
+
+	if proxy != nil {
+		target := &http.Request{URL: &url.URL{Scheme: scheme, Host: address}, Header: http.Header{}}
+
+		if proxyURL, err = proxy(target); err != nil {
+			return
+		}
+	}
+
+	if proxyURL != nil {
+		conn, err = dialThroughProxy(ctx, dial, proxyURL, address)
+	} else {
+		conn, err = dial(ctx, "tcp", address)
+	}
+
+	if err != nil || !useTLS {
+		return
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if transport, ok := c.HTTPClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(address)
+	}
+
+	TLSConn := tls.Client(conn, config)
+
+	if err = TLSConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return TLSConn, nil
+}
+
+// rawAddress returns the "host:port" address to dial for host, and whether to use TLS.
+func rawAddress(host string) (address string, useTLS bool, err error) {
+	if !strings.Contains(host, "://") {
+		if _, _, err = net.SplitHostPort(host); err != nil {
+			return "", false, fmt.Errorf("invalid raw request host %q: %w", host, err)
+		}
+
+		return host, false, nil
+	}
+
+	parsed, err := url.Parse(host)
+	if err != nil {
+		return
+	}
+
+	switch parsed.Scheme {
+	case "http":
+	case "https":
+		useTLS = true
+	default:
+		return "", false, fmt.Errorf("unsupported raw request scheme %q", parsed.Scheme)
+	}
+
+	port := parsed.Port()
+
+	if port == "" {
+		port = "80"
+
+		if useTLS {
+			port = "443"
+		}
+	}
+
+	address = net.JoinHostPort(parsed.Hostname(), port)
+
+	return
+}
@@ -0,0 +1,208 @@
+package hqgohttp
+
+// This file contains the redirect policy installed on the clients built by New.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// ErrRedirectLoop is returned when a redirect leads back to a request already made in the chain.
+var ErrRedirectLoop = errors.New("redirect loop detected")
+
+// ErrRedirectWithoutLocation is returned, with Options.StrictRedirects, when a redirect
+// response has no parseable Location header.
+var ErrRedirectWithoutLocation = errors.New("redirect without location")
+
+// ErrRedirectTimeout is returned when following a redirect chain takes longer than
+// Options.MaxRedirectDuration.
+var ErrRedirectTimeout = errors.New("redirect chain timed out")
+
+// newCheckRedirect returns the redirect policy of the clients built by New. Like the net/http
+// default, it stops after 10 redirects. In addition, it reports redirect loops, e.g. A -> B -> A,
+// as ErrRedirectLoop as soon as a request repeats, rather than when the count limit is hit.
+// With Options.AutoReferer, the Referer net/http sets on redirected requests honors the
+// Referrer-Policy of the redirect response.
+//
+// Options.RedirectStatusCodes restricts the redirects followed to the listed status codes,
+// the response of the others is returned as is. net/http only ever follows 301, 302, 303,
+// 307 and 308, so other codes can't be followed. Options.RedirectPreserveMethod lists the
+// codes of redirects keeping the method and body of the redirected request, where net/http
+// switches 301, 302 and 303 redirects of most methods to GET.
+//
+// With Options.MaxRedirectDuration, a redirect met longer than that after the start of the
+// attempt is reported as ErrRedirectTimeout rather than followed.
+func newCheckRedirect(options *Options) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		trackHop(req)
+
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+
+		if start, ok := req.Context().Value(redirectStartKey{}).(*redirectStart); ok {
+			if elapsed := start.clock.Now().Sub(start.time); elapsed > options.MaxRedirectDuration {
+				return fmt.Errorf("%w: %s after %d redirects", ErrRedirectTimeout, elapsed, len(via))
+			}
+		}
+
+		previous := via[len(via)-1]
+
+		if req.Response != nil {
+			code := req.Response.StatusCode
+
+			if len(options.RedirectStatusCodes) > 0 && !containsStatusCode(options.RedirectStatusCodes, code) {
+				return http.ErrUseLastResponse
+			}
+
+			if containsStatusCode(options.RedirectPreserveMethod, code) {
+				if err := preserveMethod(req, previous); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, previous := range via {
+			if previous.Method == req.Method && previous.URL.String() == req.URL.String() {
+				return fmt.Errorf("%w: %s %s", ErrRedirectLoop, req.Method, req.URL)
+			}
+		}
+
+		if options.AutoReferer {
+			applyReferrerPolicy(req)
+		}
+
+		return nil
+	}
+}
+
+// checkRedirectLocation reports a 301, 302, 303, 307 or 308 response without a parseable
+// Location header, a dead end net/http returns as is, as ErrRedirectWithoutLocation. The
+// body is drained and closed.
+func (c *Client) checkRedirectLocation(req *Request, res *http.Response) (err error) {
+	switch res.StatusCode {
+	case status.MovedPermanently, status.Found, status.SeeOther, status.TemporaryRedirect, status.PermanentRedirect:
+	default:
+		return
+	}
+
+	location := strings.TrimSpace(res.Header.Get(headers.Location))
+
+	if location != "" {
+		if _, err = url.Parse(location); err == nil {
+			return
+		}
+	}
+
+	c.drainBody(req, res)
+
+	err = fmt.Errorf("%w: %d response to %s %s", ErrRedirectWithoutLocation, res.StatusCode, req.Method, req.URL)
+
+	return
+}
+
+// redirectStart is the start of the redirect chain of a request attempt, read by the
+// redirect policy. The chain runs in the goroutine sending the request, no lock is needed.
+type redirectStart struct {
+	clock Clock
+	time  time.Time
+}
+
+// startRedirectChain marks the start of an attempt of the request, and of its redirect chain,
+// if Options.MaxRedirectDuration is set.
+func (c *Client) startRedirectChain(req *Request) {
+	if c.options.MaxRedirectDuration <= 0 {
+		return
+	}
+
+	if start, ok := req.Context().Value(redirectStartKey{}).(*redirectStart); ok {
+		start.time = c.clock.Now()
+
+		return
+	}
+
+	req.WithContext(context.WithValue(req.Context(), redirectStartKey{}, &redirectStart{
+		clock: c.clock,
+		time:  c.clock.Now(),
+	}))
+}
+
+// preserveMethod restores the method and body of the previous request on the redirected one.
+func preserveMethod(req, previous *http.Request) (err error) {
+	if req.Method == previous.Method {
+		return
+	}
+
+	req.Method = previous.Method
+
+	if previous.GetBody == nil {
+		if previous.ContentLength != 0 {
+			return fmt.Errorf("cannot redirect %s %s with its body: body can't be rewound", previous.Method, previous.URL)
+		}
+
+		return
+	}
+
+	if req.Body, err = previous.GetBody(); err != nil {
+		return
+	}
+
+	req.GetBody = previous.GetBody
+	req.ContentLength = previous.ContentLength
+
+	return
+}
+
+func containsStatusCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// applyReferrerPolicy removes the Referer header of a redirected request when the redirect
+// response's Referrer-Policy is no-referrer. net/http sets it to the URL redirected from,
+// or keeps the one set on the original request, and only drops it from HTTPS to HTTP.
+func applyReferrerPolicy(req *http.Request) {
+	if req.Response == nil {
+		return
+	}
+
+	var policy string
+
+	// The last policy of a list the client knows is the one applied
+	for _, token := range strings.Split(req.Response.Header.Get(headers.ReferrerPolicy), ",") {
+		if token = strings.ToLower(strings.TrimSpace(token)); referrerPolicies[token] {
+			policy = token
+		}
+	}
+
+	if policy == "no-referrer" {
+		req.Header.Del(headers.Referer)
+	}
+}
+
+// referrerPolicies lists the policies of the Referrer-Policy header.
+var referrerPolicies = map[string]bool{
+	"no-referrer":                     true,
+	"no-referrer-when-downgrade":      true,
+	"same-origin":                     true,
+	"origin":                          true,
+	"strict-origin":                   true,
+	"origin-when-cross-origin":        true,
+	"strict-origin-when-cross-origin": true,
+	"unsafe-url":                      true,
+}
+
+const maxRedirects = 10
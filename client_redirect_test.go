@@ -0,0 +1,243 @@
+package hqgohttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestAutoReferer(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if policy := r.URL.Query().Get("policy"); policy != "" {
+			w.Header().Set("Referrer-Policy", policy)
+
+			http.Redirect(w, r, "/target", http.StatusFound)
+
+			return
+		}
+
+		w.Write([]byte(r.Referer()))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		auto   bool
+		policy string
+		want   string
+	}{
+		// net/http refers to the URL redirected from, whatever the policy
+		{"disabled", false, "no-referrer", "from"},
+		{"no-referrer", true, "no-referrer", ""},
+		{"last known policy", true, "origin, No-Referrer, unknown", ""},
+		{"other policy", true, "no-referrer, origin", "from"},
+	}
+
+	for _, tt := range tests {
+		options := *DefaultOptionsSingle
+		options.AutoReferer = tt.auto
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		URL := server.URL + "/?policy=" + url.QueryEscape(tt.policy)
+
+		req, err := NewRequest(methods.Get, URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+
+		res.Body.Close()
+
+		want := tt.want
+
+		if want == "from" {
+			want = URL
+		}
+
+		if err != nil || string(body) != want {
+			t.Errorf("%s: got referer %q, %v, want %q", tt.name, body, err, want)
+		}
+	}
+}
+
+func TestStrictRedirects(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+
+		switch r.URL.Path {
+		case "/not-modified":
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			w.WriteHeader(http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		strict  bool
+		wantErr bool
+		want    int
+	}{
+		{"lenient", "/missing", false, false, http.StatusFound},
+		{"missing", "/missing", true, true, 0},
+		{"not a redirect", "/not-modified", true, false, http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&hits, 0)
+
+		options := *DefaultOptionsSingle
+		options.StrictRedirects = tt.strict
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := NewRequest(methods.Get, server.URL+tt.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(req)
+
+		if tt.wantErr {
+			if !errors.Is(err, ErrRedirectWithoutLocation) || res != nil {
+				t.Errorf("%s: got %v, %v, want ErrRedirectWithoutLocation", tt.name, res, err)
+			}
+
+			// The dead end isn't retried
+			if got := atomic.LoadInt32(&hits); got != 1 {
+				t.Errorf("%s: got %d attempts, want 1", tt.name, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		res.Body.Close()
+
+		if res.StatusCode != tt.want {
+			t.Errorf("%s: got status %d, want %d", tt.name, res.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestMaxRedirectDuration(t *testing.T) {
+	t.Parallel()
+
+	const hops = 4
+
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		hop, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+
+		if hop == hops {
+			w.Write([]byte("done"))
+
+			return
+		}
+
+		// A slow hop
+		time.Sleep(25 * time.Millisecond)
+
+		http.Redirect(w, r, "/"+strconv.Itoa(hop+1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		max     time.Duration
+		wantErr bool
+	}{
+		{"within", time.Second, false},
+		{"beyond", 40 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		atomic.StoreInt32(&requests, 0)
+
+		options := *DefaultOptionsSingle
+		options.RetryMax = 2
+		options.RetryWaitMin = time.Millisecond
+		options.RetryWaitMax = time.Millisecond
+		options.MaxRedirectDuration = tt.max
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := NewRequest(methods.Get, server.URL+"/0", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		started := time.Now()
+
+		res, err := client.Do(req)
+
+		if tt.wantErr {
+			if !errors.Is(err, ErrRedirectTimeout) {
+				t.Errorf("%s: got %v, want ErrRedirectTimeout", tt.name, err)
+			}
+
+			// Aborted at the first redirect met past the cap, not at the end of the chain
+			if elapsed := time.Since(started); elapsed >= hops*25*time.Millisecond {
+				t.Errorf("%s: aborted after %s, want before the end of the chain", tt.name, elapsed)
+			}
+
+			// The chain isn't run again: two hops, the redirect to the third being past the cap
+			if got := atomic.LoadInt32(&requests); got != 2 {
+				t.Errorf("%s: got %d requests, want 2", tt.name, got)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+
+		res.Body.Close()
+
+		if err != nil || string(body) != "done" {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, body, err, "done")
+		}
+	}
+}
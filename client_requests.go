@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptrace"
 	"net/http/httputil"
 	"os"
+	"strings"
+
+	"github.com/hueristiq/hqgohttp/headers"
 )
 
 // ErrorHandler is called if retries are expired, containing the last status
@@ -19,14 +23,16 @@ type ErrorHandler func(resp *http.Response, err error, numTries int) (*http.Resp
 // RequestLogHook allows a function to run before each retry. The HTTP
 // request which will be made, and the retry number (0 for the initial
 // request) are available to users. The internal logger is exposed to
-// consumers.
+// consumers. The tags of the request, set with WithTag, are read with
+// RequestTags(req.Context()).
 type RequestLogHook func(*http.Request, int)
 
 // ResponseLogHook is like RequestLogHook, but allows running a function
 // on each HTTP response. This function will be invoked at the end of
 // every HTTP request executed, regardless of whether a subsequent retry
 // needs to be performed or not. If the response body is read or closed
-// from this method, this will affect the response returned from Do().
+// from this method, this will affect the response returned from Do(). The
+// tags of the request are read with RequestTags(resp.Request.Context()).
 type ResponseLogHook func(*http.Response)
 
 // Request wraps the metadata needed to create HTTP requests.
@@ -51,6 +57,48 @@ func (r *Request) WithContext(ctx context.Context) *Request {
 	return r
 }
 
+// WithHeader sets the header key to value, replacing any existing values, and returns
+// the request for chaining. Headers set on the request take precedence over the client's
+// per-host and default headers.
+func (r *Request) WithHeader(key, value string) *Request {
+	r.Header.Set(key, value)
+
+	return r
+}
+
+// WithHeaders sets every header of header, replacing any existing values of those headers,
+// and returns the request for chaining.
+func (r *Request) WithHeaders(header http.Header) *Request {
+	for key, values := range header {
+		r.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+	}
+
+	return r
+}
+
+// WithContentType sets the Content-Type header and returns the request for chaining.
+func (r *Request) WithContentType(contentType string) *Request {
+	return r.WithHeader(headers.ContentType, contentType)
+}
+
+// WithTag attaches an observability tag to the request. Tags are stored in the request
+// context and never sent on the wire. Hooks can read them back with RequestTags, e.g.
+// from the *http.Request given to RequestLogHook or the response's Request given to
+// ResponseLogHook.
+func (r *Request) WithTag(key, value string) *Request {
+	parent := RequestTags(r.Context())
+
+	tags := make(Tags, len(parent)+1)
+
+	for k, v := range parent {
+		tags[k] = v
+	}
+
+	tags[key] = value
+
+	return r.WithContext(context.WithValue(r.Context(), tagsKey{}, tags))
+}
+
 // BodyBytes allows accessing the request body. It is an analogue to
 // http.Request's Body variable, but it returns a copy of the underlying data
 // rather than consuming it.
@@ -83,6 +131,7 @@ func (r *Request) Clone(ctx context.Context) *Request {
 			Type:     r.Auth.Type,
 			Username: r.Auth.Username,
 			Password: r.Auth.Password,
+			Token:    r.Auth.Token,
 		}
 	}
 
@@ -126,6 +175,17 @@ func (r *Request) hasAuth() bool {
 	return r.Auth != nil
 }
 
+// Tags are key/value pairs attached to a request for correlation in hooks.
+type Tags map[string]string
+
+// RequestTags returns the tags attached to the request owning ctx, if any.
+// The returned map must not be modified.
+func RequestTags(ctx context.Context) (tags Tags) {
+	tags, _ = ctx.Value(tagsKey{}).(Tags)
+
+	return
+}
+
 // Metrics contains the metrics about each request
 type Metrics struct {
 	// Failures is the number of failed requests
@@ -134,6 +194,39 @@ type Metrics struct {
 	Retries int
 	// DrainErrors is number of errors occurred in draining response body
 	DrainErrors int
+	// DrainAbandons is the number of chunked response bodies too large to drain,
+	// whose connection was discarded rather than reused
+	DrainAbandons int
+	// DrainTimeouts is the number of response bodies whose draining was cut short by the
+	// request context or DrainTimeout, whose connection was discarded rather than reused
+	DrainTimeouts int
+	// Class is the class of the outcome of the last attempt
+	Class ResponseClass
+	// ExpectedError is set when the request ended with one of the client's SuccessErrors
+	ExpectedError bool
+	// BytesSent is the number of bytes sent over all attempts: request lines, headers and bodies
+	BytesSent int64
+	// BytesReceived is the number of response body bytes received over all attempts, counting
+	// the bytes drained between retries and the ones read by the caller from the returned body
+	BytesReceived int64
+	// BodyHash is the hex encoded SHA-256 hash of the response body, with ComputeBodyHash.
+	// It is only set once the body has been read until io.EOF
+	BodyHash string
+	// HTTP2Downgrades is the number of attempts retried over HTTP/1.1 after an HTTP/2 error
+	HTTP2Downgrades int
+	// DecodeIncomplete is set when AutoDecompress left some of the response's content
+	// encodings undecoded, the remaining ones being in its Content-Encoding header
+	DecodeIncomplete bool
+	// MetaRefreshes lists the URLs of the meta refresh redirects followed, with FollowMetaRefresh
+	MetaRefreshes []string
+	// EndpointUsed is the base URL of the endpoint that answered, with DoWithFailover
+	EndpointUsed string
+	// RateLimit is the rate limit state reported by the last response, nil if it reported none
+	RateLimit *RateLimit
+	// NegotiatedProtocol is the ALPN protocol negotiated for the last response, e.g. "h2" or
+	// "http/1.1", be it through the main client or the HTTP/2 fallback one. It is empty for
+	// plaintext responses and servers not supporting ALPN
+	NegotiatedProtocol string
 }
 
 // Auth specific information
@@ -141,12 +234,18 @@ type Auth struct {
 	Type     AuthType
 	Username string
 	Password string
+	// Token is the token of BearerAuth credentials.
+	Token string
 }
 
+// AuthType is the scheme of the credentials, sent in answer to a WWW-Authenticate challenge
+// of the same scheme.
 type AuthType uint8
 
 const (
 	DigestAuth AuthType = iota
+	BasicAuth
+	BearerAuth
 )
 
 // FromRequest wraps an http.Request in a client.Request
@@ -216,14 +315,43 @@ func NewRequestFromURLWithContext(ctx context.Context, url, method string, body
 	// `http.NewRequestxxx` internally only uses `u.Host` and all other data is stored in `url.URL` instance
 	httpReq, err := http.NewRequestWithContext(ctx, method, url, nil) //nolint:gocritic // To be refactored
 	if err != nil {
+		// e.g. "127.0.0.1:8080", which doesn't parse without a scheme
+		if !strings.Contains(url, "://") {
+			return nil, fmt.Errorf("%w %q: missing scheme", ErrInvalidURL, url)
+		}
+
+		return nil, err
+	}
+
+	if err = validateURL(url, httpReq.URL); err != nil {
 		return nil, err
 	}
 
+	// Internationalized domain names go on the wire (Host header, SNI) as punycode
+	if httpReq.URL.Host, err = toASCIIHost(httpReq.URL.Host); err != nil {
+		return nil, err
+	}
+
+	httpReq.Host = httpReq.URL.Host
+
 	// content-length and body should be assigned only
 	// if request has body
 	if bodyReader != nil {
+		// The reusable reader rewinds itself once fully read, leaving it ready to be sent
+		var data []byte
+
+		if data, err = io.ReadAll(bodyReader); err != nil {
+			return nil, err
+		}
+
 		httpReq.ContentLength = contentLength
 		httpReq.Body = bodyReader
+		// GetBody lets net/http replay the body when following 307/308 redirects, or after a
+		// partial write, and the retries rewind it. The reusable reader only rewinds at EOF, a
+		// body read halfway would be replayed from the middle: hand out a fresh reader instead.
+		httpReq.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(data)), nil
+		}
 	}
 
 	return &Request{httpReq, Metrics{}, nil}, nil
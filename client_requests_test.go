@@ -0,0 +1,125 @@
+package hqgohttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestRedirectPreservesBody(t *testing.T) {
+	t.Parallel()
+
+	echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Write([]byte(r.Method + " " + string(body)))
+	}))
+	defer echo.Close()
+
+	for _, code := range []int{http.StatusTemporaryRedirect, http.StatusPermanentRedirect} {
+		code := code
+
+		redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+
+			http.Redirect(w, r, echo.URL, code)
+		}))
+		defer redirect.Close()
+
+		client, err := New(DefaultOptionsSingle)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Post(redirect.URL, "text/plain", "payload")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := ReadBodyString(res, 1024)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if body != "POST payload" {
+			t.Errorf("%d redirect: got %q, want %q", code, body, "POST payload")
+		}
+	}
+}
+
+func TestGetBodyReturnsWholeBody(t *testing.T) {
+	t.Parallel()
+
+	payload := strings.Repeat("x", 2000)
+
+	req, err := NewRequest(methods.Post, "http://example.com", payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read the body halfway, as an interrupted write does
+	if _, err = io.ReadFull(req.Body, make([]byte, 500)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		body, err := req.GetBody()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(data) != payload || int64(len(data)) != req.ContentLength {
+			t.Fatalf("GetBody returned %d bytes, want %d", len(data), req.ContentLength)
+		}
+	}
+}
+
+func TestTagsReachLogHooks(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("operation") != "" {
+			t.Error("got the tag sent on the wire, want it kept local")
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(DefaultOptionsSingle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requestTag, responseTag string
+
+	client.RequestLogHook = func(req *http.Request, _ int) {
+		requestTag = RequestTags(req.Context())["operation"]
+	}
+	client.ResponseLogHook = func(res *http.Response) {
+		responseTag = RequestTags(res.Request.Context())["operation"]
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req.WithTag("operation", "checkout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if requestTag != "checkout" || responseTag != "checkout" {
+		t.Fatalf("got tags %q and %q in the hooks, want checkout", requestTag, responseTag)
+	}
+}
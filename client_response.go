@@ -0,0 +1,125 @@
+package hqgohttp
+
+// This file contains helpers to safely consume HTTP response bodies.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// ErrBodyLimitExceeded is returned when a response body is larger than the read limit.
+var ErrBodyLimitExceeded = errors.New("response body exceeds read limit")
+
+// ReadBodyBytes reads up to limit bytes of the response body and always closes it.
+// If the body is larger than limit, the first limit bytes are returned along with
+// ErrBodyLimitExceeded.
+func ReadBodyBytes(resp *http.Response, limit int64) (body []byte, err error) {
+	defer resp.Body.Close()
+
+	// Read one extra byte to find out whether the limit was hit.
+	body, err = io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return
+	}
+
+	if int64(len(body)) > limit {
+		body = body[:limit]
+		err = ErrBodyLimitExceeded
+	}
+
+	return
+}
+
+// ReadBodyString is like ReadBodyBytes, but returns the body as a string.
+func ReadBodyString(resp *http.Response, limit int64) (body string, err error) {
+	var raw []byte
+
+	raw, err = ReadBodyBytes(resp, limit)
+
+	body = string(raw)
+
+	return
+}
+
+// ResponseTrailers returns the trailers sent after the response body, e.g. by gRPC-web
+// or chunked responses. The trailers are only known once the body has been read until
+// io.EOF, so read the body fully (e.g with ReadBodyBytes and a limit above the body
+// size) before calling it. Trailer names announced by the server but not yet received
+// have nil values. Body wrappers installed by the client preserve the trailers.
+func ResponseTrailers(resp *http.Response) http.Header {
+	if resp.Trailer == nil {
+		return http.Header{}
+	}
+
+	return resp.Trailer
+}
+
+// ResponseCookies returns the cookies set by the response's Set-Cookie headers.
+func ResponseCookies(resp *http.Response) []*http.Cookie {
+	return resp.Cookies()
+}
+
+// ErrEmptyBody is matched, with errors.Is, by the *EmptyBodyError returned with ErrorOnEmptyBody.
+var ErrEmptyBody = errors.New("empty response body")
+
+// EmptyBodyError is returned by Do, with Options.ErrorOnEmptyBody, for successful responses
+// without a body, e.g. from a broken upstream behind a load balancer.
+type EmptyBodyError struct {
+	// Response is the response without body, whose body is closed already.
+	Response *http.Response
+}
+
+func (e *EmptyBodyError) Error() string {
+	return fmt.Sprintf("%s: %s %s answered %s", ErrEmptyBody, e.Response.Request.Method, e.Response.Request.URL, e.Response.Status)
+}
+
+// Is reports whether target is ErrEmptyBody.
+func (e *EmptyBodyError) Is(target error) bool {
+	return target == ErrEmptyBody
+}
+
+// checkEmptyBody returns an *EmptyBodyError for 2xx responses to GET and POST requests
+// with an empty body, 204 No Content and 205 Reset Content aside. Bodies of unknown
+// length are peeked at, and restored for the caller when not empty.
+func checkEmptyBody(res *http.Response) error {
+	if res.Request == nil || (res.Request.Method != methods.Get && res.Request.Method != methods.Post) {
+		return nil
+	}
+
+	if res.StatusCode/100 != 2 || res.StatusCode == status.NoContent || res.StatusCode == status.ResetContent {
+		return nil
+	}
+
+	if res.ContentLength > 0 {
+		return nil
+	}
+
+	if res.ContentLength < 0 {
+		var peek [1]byte
+
+		n, err := io.ReadFull(res.Body, peek[:])
+
+		if n > 0 || !errors.Is(err, io.EOF) {
+			res.Body = &struct {
+				io.Reader
+				io.Closer
+			}{
+				// A read error other than io.EOF is met again by the caller
+				Reader: io.MultiReader(bytes.NewReader(peek[:n]), res.Body),
+				Closer: res.Body,
+			}
+
+			return nil
+		}
+	}
+
+	res.Body.Close()
+
+	return &EmptyBodyError{Response: res}
+}
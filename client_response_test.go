@@ -0,0 +1,134 @@
+package hqgohttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestReadBodyLimit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		body  string
+		limit int64
+		want  string
+		err   error
+	}{
+		{"short", 10, "short", nil},
+		{"exactly10!", 10, "exactly10!", nil},
+		{"longer than the limit", 10, "longer tha", ErrBodyLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		body := &closeRecorder{ReadCloser: io.NopCloser(strings.NewReader(tt.body))}
+
+		got, err := ReadBodyString(&http.Response{Body: body}, tt.limit)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("%q: got %q, %v, want %q, %v", tt.body, got, err, tt.want, tt.err)
+		}
+
+		if !body.closed {
+			t.Errorf("%q: want the body closed", tt.body)
+		}
+	}
+}
+
+func TestResponseTrailers(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Trailer", "Grpc-Status")
+
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(gzipped(t, []byte("payload")))
+		} else {
+			w.Write([]byte("payload"))
+		}
+
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	defer server.Close()
+
+	// The body wrappers of the client keep the trailers
+	options := *DefaultOptionsSingle
+	options.AutoDecompress = true
+	options.ComputeBodyHash = true
+	options.Limits.MaxResponseBodySize = 1 << 20
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{"/plain", "/gzip"} {
+		req, err := NewRequest(methods.Get, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := ReadBodyString(res, 1024)
+		if err != nil || body != "payload" {
+			t.Fatalf("%s: got %q, %v, want the payload", path, body, err)
+		}
+
+		if got := ResponseTrailers(res).Get("Grpc-Status"); got != "0" {
+			t.Fatalf("%s: got Grpc-Status trailer %q, want 0", path, got)
+		}
+	}
+}
+
+func TestResponseCookies(t *testing.T) {
+	t.Parallel()
+
+	// Echoes the cookies of the request back as Set-Cookie
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, cookie := range r.Cookies() {
+			http.SetCookie(w, &http.Cookie{Name: cookie.Name, Value: cookie.Value + "-echoed", Path: "/"})
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(DefaultOptionsSingle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	req.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	cookies := ResponseCookies(res)
+
+	got := make([]string, 0, len(cookies))
+
+	for _, cookie := range cookies {
+		got = append(got, cookie.Name+"="+cookie.Value+" "+cookie.Path)
+	}
+
+	if want := "session=abc-echoed /,theme=dark-echoed /"; strings.Join(got, ",") != want {
+		t.Fatalf("got cookies %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package hqgohttp
+
+// This file contains a high-level API for the common "fetch and use" pattern, returning the
+// response with its body already read.
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Result bundles a response, its body and the metrics of the request that got it.
+type Result struct {
+	// Response is the response, whose body is already read and closed.
+	Response *http.Response
+	// Body is the response body, truncated to RespReadLimit bytes.
+	Body []byte
+	// Truncated is set when the body was larger than RespReadLimit.
+	Truncated bool
+	// Metrics are the metrics of the request.
+	Metrics Metrics
+}
+
+// DoResult executes the request and reads the response body eagerly, up to RespReadLimit
+// bytes, closing it, so that the caller can't leak it. Larger bodies are truncated to the
+// limit, with Result.Truncated set, rather than failing the call.
+func (c *Client) DoResult(req *Request) (result *Result, err error) {
+	res, err := c.Do(req)
+	if err != nil {
+		return
+	}
+
+	limit := c.options.RespReadLimit
+
+	if limit <= 0 {
+		limit = defaultSnapshotLimit
+	}
+
+	result = &Result{Response: res}
+
+	result.Body, err = ReadBodyBytes(res, limit)
+
+	if errors.Is(err, ErrBodyLimitExceeded) {
+		result.Truncated = true
+
+		err = nil
+	}
+
+	// Read after the body, whose reads are counted in the metrics
+	result.Metrics = req.Metrics
+
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// JSON unmarshals the body into v. Truncated bodies fail with ErrBodyLimitExceeded.
+func (r *Result) JSON(v interface{}) error {
+	if r.Truncated {
+		return ErrBodyLimitExceeded
+	}
+
+	return json.Unmarshal(r.Body, v)
+}
+
+// String returns the body as a string.
+func (r *Result) String() string {
+	return string(r.Body)
+}
+
+// StatusOK reports whether the response status is a 2xx success.
+func (r *Result) StatusOK() bool {
+	return r.Response.StatusCode >= 200 && r.Response.StatusCode < 300
+}
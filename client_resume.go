@@ -0,0 +1,187 @@
+package hqgohttp
+
+// This file contains code for resuming response bodies interrupted mid-read, after Do has returned.
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// BodyInterruptedError is returned by the reads of a response body cut short by the server,
+// with io.ErrUnexpectedEOF, that Options.RetryOnBodyError couldn't resume. The request may be
+// retried as a whole.
+type BodyInterruptedError struct {
+	// Read is the number of bytes of the body read before the interruption.
+	Read int64
+	// Resumes is the number of times the body was resumed before.
+	Resumes int
+	// Err is the read error.
+	Err error
+}
+
+func (e *BodyInterruptedError) Error() string {
+	return fmt.Sprintf("response body interrupted after %d bytes and %d resumes: %v", e.Read, e.Resumes, e.Err)
+}
+
+func (e *BodyInterruptedError) Unwrap() error {
+	return e.Err
+}
+
+// resumeBody wraps the body of a response returned to the caller so that, with
+// Options.RetryOnBodyError, a read failing with io.ErrUnexpectedEOF is resumed transparently.
+//
+// Only the 200 OK responses of GET requests are resumed, GET being safe to send again, and
+// only if the server advertises `Accept-Ranges: bytes`: the request is sent again, up to
+// RetryMax times, for the missing bytes with a range request, carrying If-Range so that a
+// resource modified in between isn't stitched together. The range requests go straight to
+// the transport, without the hooks, retries and accounting of Do, and the bytes are counted
+// as read by the caller. A body that can't be resumed fails with a *BodyInterruptedError.
+func (c *Client) resumeBody(req *Request, res *http.Response) {
+	if !c.options.RetryOnBodyError || res == nil || res.Body == nil {
+		return
+	}
+
+	if req.Method != methods.Get || res.StatusCode != status.OK {
+		return
+	}
+
+	resumable := strings.EqualFold(res.Header.Get(headers.AcceptRanges), "bytes")
+
+	validator := res.Header.Get(headers.ETag)
+
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = res.Header.Get(headers.LastModified)
+	}
+
+	res.Body = &resumingReadCloser{
+		client:    c,
+		req:       req,
+		target:    res.Request,
+		body:      res.Body,
+		resumable: resumable,
+		validator: validator,
+	}
+}
+
+// resumingReadCloser reads a response body, resuming it with range requests when interrupted.
+type resumingReadCloser struct {
+	client    *Client
+	req       *Request
+	target    *http.Request
+	resumable bool
+	validator string
+	read      int64
+	resumes   int
+	mutex     sync.Mutex
+	body      io.ReadCloser
+	closed    bool
+}
+
+func (r *resumingReadCloser) Read(p []byte) (n int, err error) {
+	for {
+		r.mutex.Lock()
+		body := r.body
+		r.mutex.Unlock()
+
+		n, err = body.Read(p)
+
+		r.read += int64(n)
+
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			return
+		}
+
+		if !r.resume() {
+			return n, &BodyInterruptedError{Read: r.read, Resumes: r.resumes, Err: err}
+		}
+
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+// resume replaces the interrupted body with the rest of it, reporting whether it could.
+func (r *resumingReadCloser) resume() bool {
+	c := r.client
+
+	if !r.resumable || r.resumes >= c.getRetryMax(r.req) || r.req.Context().Err() != nil {
+		return false
+	}
+
+	r.resumes++
+
+	// Resume the last hop of the request, the URL the body comes from
+	rangeReq := &Request{Request: r.target.Clone(r.req.Context())}
+
+	rangeReq.Header.Set(headers.Range, fmt.Sprintf("bytes=%d-", r.read))
+
+	if r.validator != "" {
+		rangeReq.Header.Set(headers.IfRange, r.validator)
+	}
+
+	res, err := c.tlsClient(rangeReq, c.HTTPClient).Do(rangeReq.Request)
+	if err != nil {
+		return false
+	}
+
+	if res.StatusCode != status.PartialContent || contentRangeStart(res.Header.Get(headers.ContentRange)) != r.read {
+		res.Body.Close()
+
+		return false
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.body.Close()
+
+	if r.closed {
+		res.Body.Close()
+
+		return false
+	}
+
+	r.body = res.Body
+
+	return true
+}
+
+func (r *resumingReadCloser) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.closed = true
+
+	return r.body.Close()
+}
+
+// contentRangeStart returns the first byte position of a `bytes first-last/length`
+// Content-Range, or -1.
+func contentRangeStart(contentRange string) int64 {
+	unit, positions, found := strings.Cut(strings.TrimSpace(contentRange), " ")
+	if !found || unit != "bytes" {
+		return -1
+	}
+
+	first, _, found := strings.Cut(positions, "-")
+	if !found {
+		return -1
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+
+	return start
+}
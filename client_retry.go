@@ -3,14 +3,22 @@ package hqgohttp
 // This file contains set of Go functions that focuses on handling HTTP request retries based on specific conditions.
 
 import (
+	"bytes"
 	"context"
 	"crypto/x509"
 	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"regexp"
 )
 
+// ErrHostNotFound is returned when the host of the request does not resolve.
+// Such failures are permanent and are not retried.
+var ErrHostNotFound = errors.New("host not found")
+
 var (
 	// A regular expression to match the error returned by net/http when the
 	// configured number of redirects is exhausted. This error isn't typed
@@ -33,6 +41,24 @@ var (
 // response body before returning.
 type CheckRetry func(ctx context.Context, resp *http.Response, err error) (bool, error)
 
+// CheckRetryFull is like CheckRetry, but is also given the request, e.g. for
+// idempotency-aware or per-host policies. It takes precedence over CheckRetry.
+type CheckRetryFull func(ctx context.Context, req *http.Request, resp *http.Response, err error) (bool, error)
+
+// checkRetry runs the retry policy of the request, set with WithCheckRetry, falling back to
+// the client's, preferring CheckRetryFull over CheckRetry.
+func (c *Client) checkRetry(ctx context.Context, req *Request, res *http.Response, err error) (bool, error) {
+	if policy, ok := req.Context().Value(checkRetryKey{}).(CheckRetry); ok && policy != nil {
+		return policy(ctx, res, err)
+	}
+
+	if c.CheckRetryFull != nil {
+		return c.CheckRetryFull(ctx, req.Request, res, err)
+	}
+
+	return c.CheckRetry(ctx, res, err)
+}
+
 // DefaultRetryPolicy provides a default callback for client.CheckRetry, which
 // will retry on connection errors and server errors.
 func DefaultRetryPolicy() func(ctx context.Context, resp *http.Response, err error) (bool, error) {
@@ -45,11 +71,56 @@ func HostSprayRetryPolicy() func(ctx context.Context, resp *http.Response, err e
 	return CheckRecoverableErrors
 }
 
+// BodyAwareRetryPolicy provides a callback for client.CheckRetry, which decides
+// whether to retry a response from its status code and body, e.g. to retry a 200
+// whose JSON body reports a soft error, or to give up on a 503 whose body says
+// the failure is permanent. Connection errors are handled by CheckRecoverableErrors.
+//
+// The first RespReadLimit bytes of the body are buffered and given to fn, then put
+// back in front of the rest of the body, so the caller still reads it whole. This
+// costs an extra read and buffer copy for every response, including successful ones.
+func BodyAwareRetryPolicy(fn func(status int, body []byte) (retry bool)) func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	return func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if err != nil || resp == nil || ctx.Err() != nil {
+			return CheckRecoverableErrors(ctx, resp, err)
+		}
+
+		limit, _ := ctx.Value(respReadLimitKey{}).(int64)
+
+		if limit <= 0 {
+			limit = defaultSnapshotLimit
+		}
+
+		body, readErr := io.ReadAll(io.LimitReader(resp.Body, limit))
+
+		resp.Body = &struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: io.MultiReader(bytes.NewReader(body), resp.Body),
+			Closer: resp.Body,
+		}
+
+		// The body couldn't be read, the connection likely broke, so retry.
+		if readErr != nil {
+			return true, nil
+		}
+
+		return fn(resp.StatusCode, body), nil
+	}
+}
+
 // CheckRecoverableErrors checks if an error is recoverable and decides
 // whether to retry the request. The conditions it checks are:
 // 1. If the context has been canceled or its deadline has been exceeded, it doesn't retry.
 // 2. If the error is related to too many redirects or an unsupported protocol scheme, it doesn't retry.
 // 3. If the error is due to a TLS certificate verification failure (specifically an unknown authority error), it doesn't retry.
+// 4. If the error is due to the host not resolving (NXDOMAIN), it doesn't retry and returns ErrHostNotFound.
+// 5. If the error is due to a redirect loop (ErrRedirectLoop), it doesn't retry.
+// 6. If the error is due to a certificate pin mismatch (ErrCertPinMismatch), it doesn't retry.
+// 7. If the error is due to no TLS version in common with the server (ErrTLSVersionTooLow), it doesn't retry.
+// 8. If the error is due to response headers exceeding the limit (ErrResponseHeaderTooLarge), it doesn't retry.
+// 9. If the error is due to a redirect chain exceeding MaxRedirectDuration (ErrRedirectTimeout), it doesn't retry.
 // If none of the above conditions are met, it considers the error as likely recoverable and decides to retry.
 func CheckRecoverableErrors(ctx context.Context, _ *http.Response, err error) (bool, error) {
 	// do not retry on context.Canceled or context.DeadlineExceeded
@@ -61,6 +132,37 @@ func CheckRecoverableErrors(ctx context.Context, _ *http.Response, err error) (b
 		return false, nil
 	}
 
+	// Don't retry if the host doesn't resolve, it won't fix itself.
+	// Temporary DNS failures are still retried.
+	if isHostNotFoundError(err) {
+		return false, fmt.Errorf("%w: %w", ErrHostNotFound, err)
+	}
+
+	// Don't retry redirect loops, they will loop again.
+	if errors.Is(err, ErrRedirectLoop) {
+		return false, nil
+	}
+
+	// Don't retry slow redirect chains, they would run from the start again.
+	if errors.Is(err, ErrRedirectTimeout) {
+		return false, nil
+	}
+
+	// Don't retry certificate pin mismatches, the server won't present another certificate.
+	if errors.Is(err, ErrCertPinMismatch) {
+		return false, nil
+	}
+
+	// Don't retry TLS version mismatches, the server won't offer another version.
+	if errors.Is(err, ErrTLSVersionTooLow) {
+		return false, nil
+	}
+
+	// Don't retry oversized response headers, the server will send them again.
+	if errors.Is(err, ErrResponseHeaderTooLarge) {
+		return false, nil
+	}
+
 	var urlErr *url.Error
 
 	if errors.As(err, &urlErr) {
@@ -89,3 +191,9 @@ func isUnknownAuthorityError(err *url.Error) bool {
 
 	return errors.As(err.Err, &authorityErr)
 }
+
+func isHostNotFoundError(err error) bool {
+	var DNSErr *net.DNSError
+
+	return errors.As(err, &DNSErr) && DNSErr.IsNotFound && !DNSErr.IsTemporary
+}
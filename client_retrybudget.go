@@ -0,0 +1,66 @@
+package hqgohttp
+
+// This file contains the client-wide retry budget, a token bucket bounding the extra load retries
+// put on struggling servers, following the gRPC retry throttling approach.
+
+import (
+	"math"
+	"sync"
+)
+
+// retryBudget is a token bucket. Every request deposits ratio tokens, every retry withdraws one,
+// and retries are only allowed while a whole token is available. The bucket holds at most
+// retryBudgetMaxTokens tokens, and starts full to absorb the failures of a client's first requests.
+type retryBudget struct {
+	mutex  sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{
+		ratio:  ratio,
+		tokens: retryBudgetMaxTokens,
+	}
+}
+
+// deposit credits the bucket for a new request.
+func (b *retryBudget) deposit() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.tokens = math.Min(b.tokens+b.ratio, retryBudgetMaxTokens)
+}
+
+// withdraw takes a token for a retry, reporting whether the retry is allowed.
+func (b *retryBudget) withdraw() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+func (b *retryBudget) remaining() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.tokens
+}
+
+// RetryBudgetRemaining returns the number of retries the retry budget currently allows,
+// possibly fractional. It is +Inf when Options.RetryBudget is unset.
+func (c *Client) RetryBudgetRemaining() float64 {
+	if c.retryBudget == nil {
+		return math.Inf(1)
+	}
+
+	return c.retryBudget.remaining()
+}
+
+const retryBudgetMaxTokens = 10
@@ -0,0 +1,67 @@
+package hqgohttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestRetryBudget(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 1
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+	options.RetryBudget = 0.5
+	options.MaxConcurrentRetries = 1
+	options.SkipRetriesOverLimit = true
+	options.CheckRetry = func(_ context.Context, res *http.Response, err error) (bool, error) {
+		return err != nil || res.StatusCode >= 500, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	retries := func(n int) (total int) {
+		for i := 0; i < n; i++ {
+			req, err := NewRequest(methods.Get, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client.Do(req)
+
+			total += req.Metrics.Retries
+		}
+
+		return
+	}
+
+	// Every request deposits 0.5 token and retries once, drawing 1 token from the 10 the
+	// bucket starts with: retries stop once it's empty
+	if got := retries(40); got < 10 || got > 30 {
+		t.Fatalf("got %d retries, want the budget to allow 10 to 30", got)
+	}
+
+	if remaining := client.RetryBudgetRemaining(); remaining >= 1 {
+		t.Fatalf("%f tokens remain, want the budget exhausted", remaining)
+	}
+
+	// Requests turned down by the budget must not hold on to their retry slot, the budget
+	// replenishing lets them retry again
+	if got := retries(10); got == 0 {
+		t.Error("no retries once the budget replenished, the retry slot leaked")
+	}
+}
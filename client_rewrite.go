@@ -0,0 +1,52 @@
+package hqgohttp
+
+// This file contains the rewriting of request URLs before they are sent.
+
+import "errors"
+
+// errNilRewrite is returned by Do when Options.URLRewriter returns a nil URL.
+var errNilRewrite = errors.New("URLRewriter returned a nil URL")
+
+// rewriteURL sends req to the URL returned by Options.URLRewriter, if set, for the duration of
+// the Do call. The rewriter is given a copy of the URL, and the request gets a shallow copy of
+// its *http.Request, so neither the caller's URL nor its *http.Request are changed. The returned
+// function puts the original *http.Request back once Do is done, the response's Request being
+// the rewritten one.
+func (c *Client) rewriteURL(req *Request) (restore func(), err error) {
+	restore = func() {}
+
+	if c.options.URLRewriter == nil {
+		return
+	}
+
+	u := *req.URL
+
+	rewritten := c.options.URLRewriter(&u)
+	if rewritten == nil {
+		return restore, errNilRewrite
+	}
+
+	if err = validateURL(rewritten.String(), rewritten); err != nil {
+		return
+	}
+
+	original := req.Request
+
+	shallow := *original
+
+	shallow.URL = rewritten
+
+	if c.options.URLRewriteHost {
+		shallow.Host = rewritten.Host
+	} else if shallow.Host == "" {
+		shallow.Host = original.URL.Host
+	}
+
+	req.Request = &shallow
+
+	restore = func() {
+		req.Request = original
+	}
+
+	return
+}
@@ -0,0 +1,78 @@
+package hqgohttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestURLRewriter(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.RequestURI()))
+	}))
+	defer server.Close()
+
+	staging, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const original = "http://api.prod.invalid/v1/users?page=2"
+
+	tests := []struct {
+		name        string
+		rewriteHost bool
+		want        string
+	}{
+		{"keep host", false, "api.prod.invalid /staging/v1/users?page=2"},
+		{"rewrite host", true, staging.Host + " /staging/v1/users?page=2"},
+	}
+
+	for _, tt := range tests {
+		options := *DefaultOptionsSingle
+		options.URLRewriteHost = tt.rewriteHost
+		options.URLRewriter = func(u *url.URL) *url.URL {
+			// Rewritten in place: the rewriter is given a copy
+			u.Scheme = staging.Scheme
+			u.Host = staging.Host
+			u.Path = "/staging" + u.Path
+
+			return u
+		}
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := NewRequest(methods.Get, original, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		URL := req.URL
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+
+		res.Body.Close()
+
+		if err != nil || string(body) != tt.want {
+			t.Errorf("%s: got %q, %v, want %q", tt.name, body, err, tt.want)
+		}
+
+		if req.URL != URL || req.URL.String() != original || req.Host != "api.prod.invalid" {
+			t.Errorf("%s: the request was mutated to %s (Host %q)", tt.name, req.URL, req.Host)
+		}
+	}
+}
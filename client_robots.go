@@ -0,0 +1,347 @@
+package hqgohttp
+
+// This file contains the robots.txt policy of polite crawlers: the rules allowing or disallowing
+// paths, and the crawl delay between requests to the same host.
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+	"github.com/hueristiq/hqgohttp/status"
+)
+
+// ErrDisallowedByRobots is returned by Do for requests to paths disallowed by the robots.txt
+// policy of the client.
+var ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+
+// RobotsPolicy holds the robots.txt rules, and crawl delays, of hosts for a user agent. Hosts
+// without rules are allowed and not delayed. It is threadsafe.
+type RobotsPolicy struct {
+	userAgent string
+
+	mutex sync.Mutex
+	hosts map[string]*robotsRules
+}
+
+// robotsRules are the rules of a host, from the robots.txt group matching the user agent.
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	// next is when the next request to the host may be sent, with a crawl delay
+	next time.Time
+}
+
+type robotsRule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// NewRobotsPolicy returns an empty policy for userAgent, whose product token (e.g. "mybot" of
+// "MyBot/1.0") selects the robots.txt groups to follow, falling back to the "*" group.
+func NewRobotsPolicy(userAgent string) *RobotsPolicy {
+	token, _, _ := strings.Cut(userAgent, "/")
+
+	return &RobotsPolicy{
+		userAgent: strings.ToLower(strings.TrimSpace(token)),
+		hosts:     map[string]*robotsRules{},
+	}
+}
+
+// Add parses robotsTxt, the robots.txt of host ("host[:port]" as in URLs), replacing the rules
+// known for host. Lines that can't be parsed are ignored.
+func (p *RobotsPolicy) Add(host string, robotsTxt []byte) {
+	rules := p.parse(robotsTxt)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.hosts[strings.ToLower(host)] = rules
+}
+
+// AllowAll records host as having no robots.txt rules, e.g. when its robots.txt is missing.
+func (p *RobotsPolicy) AllowAll(host string) {
+	p.Add(host, nil)
+}
+
+// Allowed reports whether the URL may be requested. Of the rules matching its path, the
+// longest one wins, allow rules winning ties. /robots.txt itself is always allowed.
+func (p *RobotsPolicy) Allowed(u *url.URL) bool {
+	path := u.EscapedPath()
+
+	if path == "" {
+		path = "/"
+	}
+
+	if path == "/robots.txt" {
+		return true
+	}
+
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	p.mutex.Lock()
+	rules, ok := p.hosts[strings.ToLower(u.Host)]
+	p.mutex.Unlock()
+
+	if !ok {
+		return true
+	}
+
+	allowed, longest := true, -1
+
+	for _, rule := range rules.rules {
+		if rule.length < longest || (rule.length == longest && !rule.allow) || !rule.pattern.MatchString(path) {
+			continue
+		}
+
+		allowed, longest = rule.allow, rule.length
+	}
+
+	return allowed
+}
+
+// CrawlDelay returns the crawl delay of host, zero if it has none.
+func (p *RobotsPolicy) CrawlDelay(host string) time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if rules, ok := p.hosts[strings.ToLower(host)]; ok {
+		return rules.crawlDelay
+	}
+
+	return 0
+}
+
+// reserve books the next request slot of host, returning how long to wait for it.
+func (p *RobotsPolicy) reserve(host string, now time.Time) (wait time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	rules, ok := p.hosts[strings.ToLower(host)]
+	if !ok || rules.crawlDelay <= 0 {
+		return
+	}
+
+	next := rules.next
+
+	if next.Before(now) {
+		next = now
+	}
+
+	rules.next = next.Add(rules.crawlDelay)
+
+	return next.Sub(now)
+}
+
+// parse returns the rules of the groups of robotsTxt matching the user agent of the policy:
+// the groups naming its product token, compared case-insensitively as per RFC 9309, or else
+// the "*" groups. Groups naming the same agent are merged.
+func (p *RobotsPolicy) parse(robotsTxt []byte) (rules *robotsRules) {
+	type group struct {
+		agents []string
+		rules  []robotsRule
+		delay  time.Duration
+	}
+
+	var groups []*group
+
+	var current *group
+
+	scanner := bufio.NewScanner(bytes.NewReader(robotsTxt))
+
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive user-agent lines share a group
+			if current == nil || len(current.rules) > 0 || current.delay > 0 {
+				current = &group{}
+
+				groups = append(groups, current)
+			}
+
+			token, _, _ := strings.Cut(value, "/")
+
+			current.agents = append(current.agents, strings.ToLower(strings.TrimSpace(token)))
+		case "allow", "disallow":
+			if current == nil || value == "" {
+				continue
+			}
+
+			pattern, err := robotsPattern(value)
+			if err != nil {
+				continue
+			}
+
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", length: len(value), pattern: pattern})
+		case "crawl-delay":
+			if current == nil {
+				continue
+			}
+
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.delay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	rules = &robotsRules{}
+
+	best := -1
+
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			specificity := -1
+
+			switch {
+			case agent == "*":
+				specificity = 0
+			case agent != "" && agent == p.userAgent:
+				specificity = 1
+			}
+
+			if specificity < 0 || specificity < best {
+				continue
+			}
+
+			if specificity > best {
+				best = specificity
+				rules = &robotsRules{}
+			}
+
+			rules.rules = append(rules.rules, g.rules...)
+
+			if g.delay > rules.crawlDelay {
+				rules.crawlDelay = g.delay
+			}
+
+			break
+		}
+	}
+
+	return
+}
+
+// robotsPattern compiles a robots.txt path pattern, where "*" matches any sequence of
+// characters and a trailing "$" anchors the end of the path.
+func robotsPattern(value string) (*regexp.Regexp, error) {
+	anchored := strings.HasSuffix(value, "$")
+
+	value = strings.TrimSuffix(value, "$")
+
+	expression := "^" + strings.ReplaceAll(regexp.QuoteMeta(value), `\*`, ".*")
+
+	if anchored {
+		expression += "$"
+	}
+
+	return regexp.Compile(expression)
+}
+
+// WithRobotsPolicy makes Do follow policy: requests to disallowed paths fail with
+// ErrDisallowedByRobots, and requests to hosts with a crawl delay are spaced out by it, waiting
+// for their turn before being sent. Set it before using the client, a nil policy disables it.
+func (c *Client) WithRobotsPolicy(policy *RobotsPolicy) {
+	c.robots = policy
+}
+
+// FetchRobots fetches the robots.txt of the host of rawURL, e.g. "https://example.com", and adds
+// it to policy. Hosts answering with a 4xx status have no rules, other non 200 responses are
+// reported as errors.
+func (c *Client) FetchRobots(ctx context.Context, policy *RobotsPolicy, rawURL string) (err error) {
+	base, err := url.Parse(c.withDefaultScheme(rawURL))
+	if err != nil {
+		return
+	}
+
+	robotsURL := &url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/robots.txt"}
+
+	req, err := NewRequestWithContext(ctx, methods.Get, robotsURL.String(), nil)
+	if err != nil {
+		return
+	}
+
+	res, err := c.Do(req)
+	if err != nil {
+		return
+	}
+
+	limit := c.options.RespReadLimit
+
+	if limit < maxRobotsSize {
+		limit = maxRobotsSize
+	}
+
+	switch {
+	case res.StatusCode == status.OK:
+		var body []byte
+
+		// robots.txt files are truncated past the limit
+		if body, err = ReadBodyBytes(res, limit); err != nil && !errors.Is(err, ErrBodyLimitExceeded) {
+			return
+		}
+
+		policy.Add(base.Host, body)
+
+		return nil
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		res.Body.Close()
+
+		policy.AllowAll(base.Host)
+
+		return
+	default:
+		res.Body.Close()
+
+		return fmt.Errorf("fetching %s: unexpected status code %d", robotsURL, res.StatusCode)
+	}
+}
+
+// followRobots applies the robots.txt policy of the client, if any, to the request.
+func (c *Client) followRobots(req *Request) (err error) {
+	if c.robots == nil {
+		return
+	}
+
+	if !c.robots.Allowed(req.URL) {
+		return fmt.Errorf("%w: %s", ErrDisallowedByRobots, req.URL.Redacted())
+	}
+
+	wait := c.robots.reserve(req.URL.Host, c.clock.Now())
+
+	if wait <= 0 {
+		return
+	}
+
+	select {
+	case <-req.Context().Done():
+		return req.Context().Err()
+	case <-c.clock.After(wait):
+	}
+
+	return
+}
+
+// maxRobotsSize is the size robots.txt files are read up to, at least, as in RFC 9309.
+const maxRobotsSize = 500 * 1024
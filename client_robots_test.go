@@ -0,0 +1,48 @@
+package hqgohttp
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestRobotsPolicyUserAgent(t *testing.T) {
+	t.Parallel()
+
+	robotsTxt := []byte(`
+User-agent: bot
+Disallow: /bot
+
+User-agent: MyBot
+Disallow: /mybot
+
+User-agent: *
+Disallow: /all
+`)
+
+	tests := []struct {
+		userAgent  string
+		disallowed string
+	}{
+		// The product token is matched whole, case-insensitively
+		{"mybot/1.0", "/mybot"},
+		{"MYBOT", "/mybot"},
+		// Not as a substring
+		{"robot/2.0", "/all"},
+		{"mybotnet/1.0", "/all"},
+		{"Bot/3.0 (+https://example.com)", "/bot"},
+	}
+
+	for _, tt := range tests {
+		policy := NewRobotsPolicy(tt.userAgent)
+
+		policy.Add("example.com", robotsTxt)
+
+		for _, path := range []string{"/bot", "/mybot", "/all"} {
+			allowed := policy.Allowed(&url.URL{Scheme: "https", Host: "example.com", Path: path})
+
+			if want := path != tt.disallowed; allowed != want {
+				t.Errorf("%s: got %s allowed %v, want %v", tt.userAgent, path, allowed, want)
+			}
+		}
+	}
+}
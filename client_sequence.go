@@ -0,0 +1,91 @@
+package hqgohttp
+
+// This file contains code for sending requests one after the other and reporting how the client
+// reused connections across them.
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+)
+
+// SequenceStats reports the connections used by the requests of a DoSequence call.
+type SequenceStats struct {
+	// Attempts is the number of connections obtained, one per attempt, retries included
+	Attempts int
+	// Connections is the number of distinct connections used
+	Connections int
+	// Reused is the number of attempts sent over a connection that had served a previous request
+	Reused int
+}
+
+// DoSequence executes the requests one after the other, in order, so that they may all go over
+// the same connection, and reports in stats the connections used, as seen by httptrace.
+//
+// Go's transport doesn't pipeline requests: a request is only written once the response to the
+// previous one was read, so this diagnoses connection reuse, not pipelining. To that end, every
+// response body is read whole and closed, releasing the connection, before the next request is
+// sent; the responses returned carry the buffered bodies. Connections are only reused with
+// keep-alives enabled, which the default transport of the client disables: use a pooled one,
+// e.g. with Options.HTTPClient set to DefaultPooledClient().
+//
+// The sequence stops at the first error, which is returned along with the responses so far.
+func (c *Client) DoSequence(reqs []*Request) (responses []*http.Response, stats SequenceStats, err error) {
+	seen := make(map[net.Conn]bool)
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(connInfo httptrace.GotConnInfo) {
+			stats.Attempts++
+
+			if connInfo.Reused {
+				stats.Reused++
+			}
+
+			if !seen[connInfo.Conn] {
+				seen[connInfo.Conn] = true
+
+				stats.Connections++
+			}
+		},
+	}
+
+	for _, req := range reqs {
+		var res *http.Response
+
+		if res, err = c.doTraced(req, trace); err != nil {
+			return
+		}
+
+		var body []byte
+
+		body, err = io.ReadAll(res.Body)
+
+		res.Body.Close()
+
+		res.Body = io.NopCloser(bytes.NewReader(body))
+
+		responses = append(responses, res)
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// doTraced executes the request with trace, on a shallow copy of it moved to a context with
+// trace, leaving the context of the caller's request untouched.
+func (c *Client) doTraced(req *Request, trace *httptrace.ClientTrace) (res *http.Response, err error) {
+	original := req.Request
+
+	defer func() {
+		req.Request = original
+	}()
+
+	req.Request = original.WithContext(httptrace.WithClientTrace(original.Context(), trace))
+
+	return c.Do(req)
+}
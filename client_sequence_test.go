@@ -0,0 +1,51 @@
+package hqgohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestDoSequence(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.HTTPClient = DefaultPooledClient()
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := make([]*Request, 3)
+
+	for i := range reqs {
+		if reqs[i], err = NewRequest(methods.Get, server.URL, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	responses, stats, err := client.DoSequence(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(responses) != 3 || stats.Attempts != 3 || stats.Connections != 1 || stats.Reused != 2 {
+		t.Fatalf("got %d responses and stats %+v, want 3 requests over 1 connection", len(responses), stats)
+	}
+
+	// The requests are traced on copies, the caller's ones keep their context
+	for _, req := range reqs {
+		if httptrace.ContextClientTrace(req.Context()) != nil {
+			t.Fatal("got the trace in the context of the caller's request")
+		}
+	}
+}
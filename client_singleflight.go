@@ -0,0 +1,132 @@
+package hqgohttp
+
+// This file contains code for coalescing concurrent identical requests into a single in-flight call.
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/headers"
+	"github.com/hueristiq/hqgohttp/methods"
+	"golang.org/x/sync/singleflight"
+)
+
+// sharedResponse is the result of a coalesced call: the response, its buffered body and the
+// metrics of the request that got it.
+type sharedResponse struct {
+	res     *http.Response
+	body    []byte
+	metrics Metrics
+}
+
+// coalescedHeaders lists the request headers that may change the response and therefore
+// take part in the key identifying identical requests.
+var coalescedHeaders = []string{
+	headers.Accept,
+	headers.AcceptEncoding,
+	headers.AcceptLanguage,
+	headers.Authorization,
+	headers.Cookie,
+	headers.Range,
+}
+
+// isCoalescable reports whether the request is safe to share with concurrent identical requests.
+func isCoalescable(req *Request) bool {
+	return (req.Method == methods.Get || req.Method == methods.Head) && req.Body == nil
+}
+
+// doShared executes the request through the client's single flight group, so concurrent
+// identical requests share one call. Each caller receives an independent response copy.
+//
+// The call is made with a copy of the request detached from the cancellation of the caller
+// that started it, so that the others don't fail along if that one gives up, Options.Timeout
+// still bounding it. Every caller waits for the call until its own context is done.
+func (c *Client) doShared(req *Request) (res *http.Response, err error) {
+	flight := c.flights.DoChan(flightKey(req), func() (interface{}, error) {
+		flightReq := &Request{Request: req.Request.Clone(detachedContext{req.Context()}), Auth: req.Auth}
+
+		flightRes, flightErr := c.do(flightReq)
+		if flightErr != nil {
+			return nil, flightErr
+		}
+
+		body, flightErr := bufferBody(flightRes, c.bufferedBodyLimit())
+		if flightErr != nil {
+			return nil, flightErr
+		}
+
+		return &sharedResponse{res: flightRes, body: body, metrics: flightReq.Metrics}, nil
+	})
+
+	var result singleflight.Result
+
+	select {
+	case result = <-flight:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	shared, _ := result.Val.(*sharedResponse)
+
+	req.Metrics = shared.metrics
+	req.Metrics.MetaRefreshes = append([]string(nil), shared.metrics.MetaRefreshes...)
+
+	copied := *shared.res
+
+	copied.Header = shared.res.Header.Clone()
+	copied.Trailer = shared.res.Trailer.Clone()
+	copied.Body = io.NopCloser(bytes.NewReader(shared.body))
+
+	if shared.res.Request != nil {
+		copied.Request = shared.res.Request.Clone(req.Context())
+	}
+
+	res = &copied
+
+	return
+}
+
+// detachedContext carries the values of its parent but is never canceled nor has a deadline.
+type detachedContext struct {
+	context.Context //nolint:containedctx // Only its values are used
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+// flightKey identifies identical requests by method, URL and the headers that may change the response.
+func flightKey(req *Request) string {
+	var key strings.Builder
+
+	key.WriteString(req.Method)
+	key.WriteString(" ")
+	key.WriteString(req.URL.String())
+
+	for _, header := range coalescedHeaders {
+		for _, value := range req.Header.Values(header) {
+			key.WriteString("\n")
+			key.WriteString(header)
+			key.WriteString(": ")
+			key.WriteString(value)
+		}
+	}
+
+	return key.String()
+}
@@ -0,0 +1,114 @@
+package hqgohttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestSingleFlightLeaderCanceled(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt32(&hits, 1)
+
+		time.Sleep(200 * time.Millisecond)
+
+		w.Header().Set("X-Shared", "yes")
+		w.Write([]byte("shared"))
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.SingleFlight = true
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		req, err := NewRequestWithContext(ctx, methods.Get, server.URL, nil)
+		if err != nil {
+			t.Error(err)
+
+			return
+		}
+
+		if _, err := client.Do(req); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v for the leader, want its context deadline", err)
+		}
+	}()
+
+	// Let the leader start the flight
+	time.Sleep(20 * time.Millisecond)
+
+	responses := make([]*http.Response, 2)
+
+	for i := range responses {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			req, err := NewRequest(methods.Get, server.URL, nil)
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			if responses[i], err = client.Do(req); err != nil {
+				t.Errorf("got %v for a follower, want the shared response", err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if t.Failed() {
+		return
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("got %d calls, want 1", got)
+	}
+
+	for _, res := range responses {
+		body, err := io.ReadAll(res.Body)
+		if err != nil || string(body) != "shared" {
+			t.Fatalf("got %q, %v, want the shared body", body, err)
+		}
+	}
+
+	// The copies don't share their headers nor requests
+	responses[0].Header.Set("X-Shared", "no")
+	responses[0].Request.Header.Set("X-Mutated", "yes")
+
+	if got := responses[1].Header.Get("X-Shared"); got != "yes" {
+		t.Fatalf("got X-Shared %q, want the response headers independent", got)
+	}
+
+	if responses[0].Request == responses[1].Request || responses[1].Request.Header.Get("X-Mutated") != "" {
+		t.Fatal("want the response requests independent")
+	}
+}
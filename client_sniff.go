@@ -0,0 +1,81 @@
+package hqgohttp
+
+// This file contains the sniffing of the content type of request bodies sent without one.
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/hueristiq/hqgohttp/headers"
+)
+
+// sniffContentType sets the Content-Type of a request with a body but no Content-Type, when
+// Options.SniffContentType is set, to the type http.DetectContentType detects from the first
+// 512 bytes of the body. Replayable bodies are peeked at through an independent copy from
+// GetBody, others, GetBody handing out the body itself included, are read from and restored.
+func (c *Client) sniffContentType(req *Request) (err error) {
+	if !c.options.SniffContentType || req.Body == nil || req.Body == http.NoBody || req.Header.Get(headers.ContentType) != "" {
+		return
+	}
+
+	var (
+		peeked []byte
+		body   io.ReadCloser
+	)
+
+	if req.GetBody != nil {
+		if body, err = req.GetBody(); err != nil {
+			return
+		}
+	}
+
+	if body != nil && body != req.Body {
+		defer body.Close()
+
+		if peeked, err = peek(body); err != nil {
+			return
+		}
+	} else {
+		if peeked, err = peek(req.Body); err != nil {
+			return
+		}
+
+		rest := io.Reader(req.Body)
+
+		// The body ended within the peek, some readers rewind once read whole
+		if len(peeked) < sniffLength {
+			rest = http.NoBody
+		}
+
+		req.Body = &struct {
+			io.Reader
+			io.Closer
+		}{
+			Reader: io.MultiReader(bytes.NewReader(peeked), rest),
+			Closer: req.Body,
+		}
+	}
+
+	if len(peeked) > 0 {
+		req.Header.Set(headers.ContentType, http.DetectContentType(peeked))
+	}
+
+	return
+}
+
+// peek reads up to sniffLength bytes from r, less only if r ends first.
+func peek(r io.Reader) (data []byte, err error) {
+	data = make([]byte, sniffLength)
+
+	n, err := io.ReadFull(r, data)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = nil
+	}
+
+	return data[:n], err
+}
+
+// sniffLength is the number of bytes http.DetectContentType considers.
+const sniffLength = 512
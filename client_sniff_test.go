@@ -0,0 +1,89 @@
+package hqgohttp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestSniffContentType(t *testing.T) {
+	t.Parallel()
+
+	type received struct {
+		contentType string
+		body        string
+	}
+
+	got := make(chan received, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		got <- received{r.Header.Get("Content-Type"), string(body)}
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 0
+	options.SniffContentType = true
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := "<html><body>" + strings.Repeat("x", 2000) + "</body></html>"
+
+	for _, name := range []string{"replayable", "stream"} {
+		req, err := NewRequest(methods.Post, server.URL, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A body that can't be replayed, read from and restored
+		if name == "stream" {
+			req.Body = io.NopCloser(bytes.NewReader([]byte(payload)))
+			req.GetBody = nil
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		res.Body.Close()
+
+		r := <-got
+
+		if r.body != payload {
+			t.Errorf("%s: server got %d bytes, want %d", name, len(r.body), len(payload))
+		}
+
+		if !strings.HasPrefix(r.contentType, "text/html") {
+			t.Errorf("%s: got Content-Type %q, want text/html", name, r.contentType)
+		}
+	}
+
+	req, err := NewRequest(methods.Post, server.URL, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if r := <-got; r.contentType != "application/octet-stream" {
+		t.Errorf("caller Content-Type replaced with %q", r.contentType)
+	}
+}
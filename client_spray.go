@@ -0,0 +1,129 @@
+package hqgohttp
+
+// This file contains code for spraying requests across many hosts while reusing
+// connections for URLs that share the same host.
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// SprayResult holds the outcome of a single request issued by SprayHosts.
+// If Response is not nil, it is up to the caller to close its body.
+type SprayResult struct {
+	URL      string
+	Response *http.Response
+	Err      error
+}
+
+// SprayHosts issues GET requests to the given URLs, grouped by host. Host groups are
+// processed concurrently, spreading the load across hosts, while the URLs within a
+// group are requested one after another, perHostDelay apart, over a connection pool
+// dedicated to that group so its connections get reused.
+//
+// The returned channel receives one result per URL and is closed once all URLs have
+// been processed or the context is done.
+func (c *Client) SprayHosts(ctx context.Context, URLs []string, perHostDelay time.Duration) <-chan SprayResult {
+	results := make(chan SprayResult, len(URLs))
+
+	groups := map[string][]string{}
+	hosts := []string{}
+
+	for _, URL := range URLs {
+		URL = c.withDefaultScheme(URL)
+
+		parsed, err := url.Parse(URL)
+		if err != nil {
+			results <- SprayResult{URL: URL, Err: err}
+
+			continue
+		}
+
+		if _, ok := groups[parsed.Host]; !ok {
+			hosts = append(hosts, parsed.Host)
+		}
+
+		groups[parsed.Host] = append(groups[parsed.Host], URL)
+	}
+
+	wg := &sync.WaitGroup{}
+
+	for _, host := range hosts {
+		wg.Add(1)
+
+		go func(URLs []string) {
+			defer wg.Done()
+
+			c.sprayHost(ctx, URLs, perHostDelay, results)
+		}(groups[host])
+	}
+
+	go func() {
+		wg.Wait()
+
+		close(results)
+	}()
+
+	return results
+}
+
+// sprayHost requests URLs sharing the same host sequentially over a dedicated connection pool.
+func (c *Client) sprayHost(ctx context.Context, URLs []string, perHostDelay time.Duration, results chan<- SprayResult) {
+	HTTPClient := c.HTTPClient
+
+	if transport, ok := c.HTTPClient.Transport.(*http.Transport); ok {
+		transport = transport.Clone()
+		transport.DisableKeepAlives = false
+		transport.MaxIdleConnsPerHost = 1
+
+		HTTPClient = &http.Client{
+			Transport:     transport,
+			CheckRedirect: c.HTTPClient.CheckRedirect,
+			Jar:           c.HTTPClient.Jar,
+			Timeout:       c.HTTPClient.Timeout,
+		}
+
+		defer HTTPClient.CloseIdleConnections()
+	}
+
+	ctx = context.WithValue(ctx, httpClientKey{}, HTTPClient)
+
+	for i, URL := range URLs {
+		if i > 0 && perHostDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c.clock.After(perHostDelay):
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		req, err := NewRequestWithContext(ctx, methods.Get, URL, nil)
+		if err != nil {
+			results <- SprayResult{URL: URL, Err: err}
+
+			continue
+		}
+
+		release, err := c.acquireGlobalSlot(ctx)
+		if err != nil {
+			results <- SprayResult{URL: URL, Err: err}
+
+			return
+		}
+
+		res, err := c.Do(req)
+
+		release()
+
+		results <- SprayResult{URL: URL, Response: res, Err: err}
+	}
+}
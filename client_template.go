@@ -0,0 +1,113 @@
+package hqgohttp
+
+// This file contains request templates, rendering many requests that differ only by the values
+// substituted in them.
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ErrMissingTemplateVar is returned by RequestTemplate.Render when a placeholder has no value.
+var ErrMissingTemplateVar = errors.New("missing template variable")
+
+// RequestTemplate is a request whose URL, header values and body contain placeholders, e.g.
+// {{.FUZZ}}, to substitute with values. It can be rendered concurrently.
+type RequestTemplate struct {
+	Method string
+	// URL holds placeholders anywhere, their values being escaped: as path segments up to the
+	// query, e.g. "a/b" as "a%2Fb", and as query components from there, e.g. "a&b" as "a%26b".
+	URL string
+	// Header values hold placeholders, whose values must not contain line breaks.
+	Header http.Header
+	// Body holds placeholders, substituted as is.
+	Body string
+}
+
+// Render returns the request of the template with the placeholders substituted by their value
+// in vars. It fails with ErrMissingTemplateVar, naming them, if some placeholders have no value.
+func (t *RequestTemplate) Render(vars map[string]string) (req *Request, err error) {
+	if missing := t.missingVars(vars); len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrMissingTemplateVar, strings.Join(missing, ", "))
+	}
+
+	URL := t.URL
+
+	query := strings.IndexAny(URL, "?#")
+
+	if query < 0 {
+		query = len(URL)
+	}
+
+	URL = substitute(URL[:query], vars, url.PathEscape) + substitute(URL[query:], vars, url.QueryEscape)
+
+	var body interface{}
+
+	if t.Body != "" {
+		body = substitute(t.Body, vars, nil)
+	}
+
+	if req, err = NewRequest(t.Method, URL, body); err != nil {
+		return
+	}
+
+	for name, values := range t.Header {
+		for _, value := range values {
+			value = substitute(value, vars, nil)
+
+			if strings.ContainsAny(value, "\r\n") {
+				return nil, fmt.Errorf("header %s: value with a line break", name)
+			}
+
+			req.Header[name] = append(req.Header[name], value)
+		}
+	}
+
+	return
+}
+
+// missingVars returns the sorted names of the placeholders of the template without a value.
+func (t *RequestTemplate) missingVars(vars map[string]string) (missing []string) {
+	seen := map[string]bool{}
+
+	texts := []string{t.URL, t.Body}
+
+	for _, values := range t.Header {
+		texts = append(texts, values...)
+	}
+
+	for _, text := range texts {
+		for _, match := range placeholderRegex.FindAllStringSubmatch(text, -1) {
+			if _, ok := vars[match[1]]; !ok && !seen[match[1]] {
+				seen[match[1]] = true
+
+				missing = append(missing, match[1])
+			}
+		}
+	}
+
+	sort.Strings(missing)
+
+	return
+}
+
+// substitute replaces the placeholders of text with their value, escaped by escape if not nil.
+func substitute(text string, vars map[string]string, escape func(string) string) string {
+	return placeholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		value := vars[placeholderRegex.FindStringSubmatch(placeholder)[1]]
+
+		if escape != nil {
+			value = escape(value)
+		}
+
+		return value
+	})
+}
+
+// placeholderRegex matches the placeholders of templates, e.g. {{.FUZZ}} or {{ .FUZZ }}.
+var placeholderRegex = regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}`)
@@ -0,0 +1,115 @@
+package hqgohttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestRequestTemplate(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Write([]byte(r.URL.EscapedPath() + " " + r.URL.Query().Get("q") + " " + r.Header.Get("X-Word") + " " + string(body)))
+	}))
+	defer server.Close()
+
+	client, err := New(DefaultOptionsSingle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &RequestTemplate{
+		Method: methods.Post,
+		URL:    server.URL + "/users/{{.FUZZ}}/profile?q={{ .FUZZ }}",
+		Header: http.Header{"X-Word": {"<{{.FUZZ}}>"}},
+		Body:   `{"word":"{{.FUZZ}}"}`,
+	}
+
+	words := []string{"admin", "a/b", "..", "a b", "a&b=c", "%2e", "é"}
+
+	for _, word := range words {
+		req, err := template.Render(map[string]string{"FUZZ": word})
+		if err != nil {
+			t.Fatalf("%q: %v", word, err)
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%q: %v", word, err)
+		}
+
+		body, err := io.ReadAll(res.Body)
+
+		res.Body.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := "/users/" + url.PathEscape(word) + "/profile " + word + " <" + word + `> {"word":"` + word + `"}`
+
+		if string(body) != want {
+			t.Errorf("%q: got %q, want %q", word, body, want)
+		}
+	}
+
+	_, err = (&RequestTemplate{
+		Method: methods.Get,
+		URL:    server.URL + "/{{.A}}/{{.B}}",
+		Header: http.Header{"X-C": {"{{.C}}"}},
+	}).Render(map[string]string{"A": "a"})
+	if !errors.Is(err, ErrMissingTemplateVar) || !strings.HasSuffix(err.Error(), "B, C") {
+		t.Fatalf("got %v, want ErrMissingTemplateVar for B, C", err)
+	}
+
+	_, err = (&RequestTemplate{
+		Method: methods.Get,
+		URL:    server.URL,
+		Header: http.Header{"X-Word": {"{{.FUZZ}}"}},
+	}).Render(map[string]string{"FUZZ": "a\r\nX-Injected: 1"})
+	if err == nil {
+		t.Fatal("rendered a header value with a line break")
+	}
+}
+
+func FuzzRequestTemplatePath(f *testing.F) {
+	for _, word := range []string{"admin", "a/b", "..", "?x=1#y", "a b", "%zz", "\xff"} {
+		f.Add(word)
+	}
+
+	template := &RequestTemplate{
+		Method: methods.Get,
+		URL:    "http://example.com/users/{{.FUZZ}}/profile?q={{.FUZZ}}",
+	}
+
+	f.Fuzz(func(t *testing.T, word string) {
+		req, err := template.Render(map[string]string{"FUZZ": word})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The value stays within its path segment and query parameter
+		segment := strings.TrimSuffix(strings.TrimPrefix(req.URL.EscapedPath(), "/users/"), "/profile")
+
+		if unescaped, err := url.PathUnescape(segment); err != nil || unescaped != word {
+			t.Fatalf("got path segment %q (%q, %v), want %q", segment, unescaped, err, word)
+		}
+
+		if got := req.URL.Query().Get("q"); got != word {
+			t.Fatalf("got query parameter %q, want %q", got, word)
+		}
+
+		if req.URL.Host != "example.com" {
+			t.Fatalf("got host %q, want example.com", req.URL.Host)
+		}
+	})
+}
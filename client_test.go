@@ -0,0 +1,206 @@
+package hqgohttp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestGiveUpErrorAttempts(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	URL := server.URL
+	server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 0
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+	options.CheckRetry = func(_ context.Context, _ *http.Response, err error) (bool, error) {
+		return err != nil, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The retry max of the request, not the client's, bounds the attempts kept
+	req, err := NewRequestWithContext(WithRetryMax(context.Background(), 3), methods.Get, URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Do(req)
+
+	var giveUp *GiveUpError
+
+	if !errors.As(err, &giveUp) {
+		t.Fatalf("got %v, want a *GiveUpError", err)
+	}
+
+	if giveUp.Tries != 4 || len(giveUp.Attempts) != 4 {
+		t.Fatalf("got %d tries and %d attempt errors, want 4 of each", giveUp.Tries, len(giveUp.Attempts))
+	}
+}
+
+// closeRecorder records whether the body it wraps was closed.
+type closeRecorder struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+
+	return c.ReadCloser.Close()
+}
+
+func TestResponseHookErrorClosesBody(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+
+	errHook := errors.New("rejected")
+
+	var body *closeRecorder
+
+	options := *DefaultOptionsSingle
+	options.ResponseHook = func(res *http.Response) (*http.Response, error) {
+		body = &closeRecorder{ReadCloser: res.Body}
+
+		res.Body = body
+
+		return res, errHook
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req)
+	if !errors.Is(err, errHook) || res != nil {
+		t.Fatalf("got %v, %v, want the hook error alone", res, err)
+	}
+
+	if !body.closed {
+		t.Fatal("want the body of the rejected response closed")
+	}
+}
+
+func TestTimeoutAdjustFactor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		factor   float64
+		noAdjust bool
+		want     time.Duration
+	}{
+		{"default factor", 25 * time.Second, 0, false, 7500 * time.Millisecond},
+		{"below a second", 16 * time.Second, 0.05, false, 800 * time.Millisecond},
+		{"factor of 1", 20 * time.Second, 1, false, 20 * time.Second},
+		{"NoAdjustTimeout", 20 * time.Second, 0.5, true, 20 * time.Second},
+		{"short timeout", 15 * time.Second, 0.5, false, 15 * time.Second},
+	}
+
+	for _, tt := range tests {
+		options := *DefaultOptionsSingle
+		options.Timeout = tt.timeout
+		options.RetryMax = 2
+		options.TimeoutAdjustFactor = tt.factor
+		options.NoAdjustTimeout = tt.noAdjust
+
+		client, err := New(&options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := client.HTTPClient.Timeout; got != tt.want {
+			t.Errorf("%s: got timeout %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMinimal(t *testing.T) {
+	t.Parallel()
+
+	hits := make(chan string, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits <- r.Header.Get("X-Default") + " " + r.Header.Get("X-Host")
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.Minimal = true
+	options.MaxConcurrentRequests = 1
+	options.DefaultHeaders = http.Header{"X-Default": {"default"}}
+	options.PerHostHeaders = map[string]http.Header{"127.0.0.1": {"X-Host": {"host"}}}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func() (*http.Response, error) {
+		req, err := NewRequest(methods.Get, server.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		return client.Do(req)
+	}
+
+	first, err := get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := <-hits; got != "default host" {
+		t.Fatalf("got headers %q, want %q", got, "default host")
+	}
+
+	second := make(chan error, 1)
+
+	go func() {
+		res, err := get()
+		if err == nil {
+			res.Body.Close()
+		}
+
+		second <- err
+	}()
+
+	// The slot of the first request is held until its body is closed
+	select {
+	case <-hits:
+		t.Fatal("a second request was sent with the first one in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Body.Close()
+
+	if err := <-second; err != nil {
+		t.Fatal(err)
+	}
+
+	<-hits
+}
@@ -0,0 +1,95 @@
+package hqgohttp
+
+// This file contains the per-request bandwidth throttling of request and response bodies.
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// throttledReadCloser caps the throughput of reads to rate bytes per second with a token
+// bucket holding up to rate tokens, one per byte, refilled at rate tokens per second. The
+// bucket starts full, so bursts, e.g. after a pause, are bounded to a second worth of data.
+// Reads are capped to rate bytes, and wait for the tokens they drew beyond those available.
+type throttledReadCloser struct {
+	io.ReadCloser
+	ctx   context.Context
+	clock Clock
+	rate  int64
+	// tokens is the number of bytes that may be read without waiting, negative once drawn in
+	// advance, as of last
+	tokens float64
+	last   time.Time
+}
+
+func (r *throttledReadCloser) Read(p []byte) (n int, err error) {
+	if int64(len(p)) > r.rate {
+		p = p[:r.rate]
+	}
+
+	n, err = r.ReadCloser.Read(p)
+
+	now := r.clock.Now()
+
+	if r.last.IsZero() {
+		r.tokens = float64(r.rate)
+	} else {
+		r.tokens += now.Sub(r.last).Seconds() * float64(r.rate)
+
+		if r.tokens > float64(r.rate) {
+			r.tokens = float64(r.rate)
+		}
+	}
+
+	r.last = now
+
+	r.tokens -= float64(n)
+
+	if r.tokens >= 0 {
+		return
+	}
+
+	wait := time.Duration(-r.tokens / float64(r.rate) * float64(time.Second))
+
+	select {
+	case <-r.ctx.Done():
+		return n, r.ctx.Err()
+	case <-r.clock.After(wait):
+	}
+
+	return
+}
+
+// throttleRequestBody caps the upload throughput of the request body to Options.MaxBytesPerSecond.
+func (c *Client) throttleRequestBody(req *Request) {
+	if c.options.MaxBytesPerSecond <= 0 || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	// Bodies that were not rewound are throttled already
+	if _, ok := req.Body.(*throttledReadCloser); ok {
+		return
+	}
+
+	req.Body = c.throttle(req.Context(), req.Body)
+}
+
+// throttleResponseBody caps the download throughput of the response body to Options.MaxBytesPerSecond.
+func (c *Client) throttleResponseBody(ctx context.Context, res *http.Response) {
+	if c.options.MaxBytesPerSecond <= 0 || res == nil || res.Body == nil {
+		return
+	}
+
+	res.Body = c.throttle(ctx, res.Body)
+}
+
+func (c *Client) throttle(ctx context.Context, body io.ReadCloser) io.ReadCloser {
+	return &throttledReadCloser{
+		ReadCloser: body,
+		ctx:        ctx,
+		clock:      c.clock,
+		rate:       c.options.MaxBytesPerSecond,
+	}
+}
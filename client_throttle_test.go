@@ -0,0 +1,81 @@
+package hqgohttp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestThrottleTokenBucket(t *testing.T) {
+	t.Parallel()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+
+	body := &throttledReadCloser{
+		ReadCloser: io.NopCloser(bytes.NewReader(make([]byte, 8000))),
+		ctx:        context.Background(),
+		clock:      clock,
+		rate:       1000,
+	}
+
+	read := func(n int) time.Duration {
+		start := clock.Now()
+
+		if _, err := io.CopyBuffer(io.Discard, io.LimitReader(struct{ io.Reader }{body}, int64(n)), make([]byte, 100)); err != nil {
+			t.Fatal(err)
+		}
+
+		return clock.Now().Sub(start)
+	}
+
+	// The full bucket lets a second worth of data through, the rest at the rate
+	if elapsed := read(5000); elapsed != 4*time.Second {
+		t.Fatalf("read 5000 bytes in %s, want 4s", elapsed)
+	}
+
+	// A pause refills the bucket up to its capacity only
+	clock.advance(time.Minute)
+
+	if elapsed := read(3000); elapsed != 2*time.Second {
+		t.Fatalf("read 3000 bytes after a pause in %s, want 2s", elapsed)
+	}
+}
+
+func BenchmarkThrottleDisabled(b *testing.B) {
+	client, err := New(DefaultOptionsSingle)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	payload := make([]byte, 64<<10)
+
+	buf := make([]byte, 32<<10)
+
+	read := func(b *testing.B, throttle bool) {
+		b.SetBytes(int64(len(payload)))
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			res := &http.Response{Body: io.NopCloser(bytes.NewReader(payload))}
+
+			if throttle {
+				client.throttleResponseBody(context.Background(), res)
+			}
+
+			if _, err := io.CopyBuffer(io.Discard, struct{ io.Reader }{res.Body}, buf); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("baseline", func(b *testing.B) {
+		read(b, false)
+	})
+
+	b.Run("disabled", func(b *testing.B) {
+		read(b, true)
+	})
+}
@@ -0,0 +1,73 @@
+package hqgohttp
+
+// This file contains the enforcement of a TLS version range on connections, and the reporting
+// of servers that don't support it.
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrTLSVersionTooLow is returned when the TLS handshake fails because the server and the
+// client have no TLS version in common, e.g. a server offering TLS 1.0 only to a client
+// requiring TLS 1.2 or above. It is not retried.
+var ErrTLSVersionTooLow = errors.New("no TLS version in common with the server")
+
+// applyTLSVersions restricts the TLS versions of the transport to the range of the options.
+// A MaxTLSVersion below the default minimum, TLS 1.2, without a MinTLSVersion lowers the
+// minimum to it, the range would be empty otherwise.
+func applyTLSVersions(transport *http.Transport, options *Options) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+
+	if options.MinTLSVersion != 0 {
+		config.MinVersion = options.MinTLSVersion
+	}
+
+	if options.MaxTLSVersion != 0 {
+		config.MaxVersion = options.MaxTLSVersion
+
+		if options.MinTLSVersion == 0 && config.MinVersion > config.MaxVersion {
+			config.MinVersion = config.MaxVersion
+		}
+	}
+
+	transport.TLSClientConfig = config
+}
+
+// tlsVersionError wraps err with ErrTLSVersionTooLow if it is a TLS version mismatch: either
+// the server rejected the versions offered with a protocol_version alert, it selected one
+// outside of the range, or the range itself is empty, e.g. a TLS config of the caller's own
+// with a minimum above the MaxTLSVersion. crypto/tls doesn't type these errors, which are
+// matched by messages.
+func tlsVersionError(err error) error {
+	if err == nil || errors.Is(err, ErrTLSVersionTooLow) {
+		return err
+	}
+
+	message := err.Error()
+
+	if strings.Contains(message, "tls: protocol version not supported") ||
+		strings.Contains(message, "tls: server selected unsupported protocol version") ||
+		strings.Contains(message, "tls: no supported versions satisfy MinVersion and MaxVersion") {
+		return fmt.Errorf("%w: %w", ErrTLSVersionTooLow, err)
+	}
+
+	return err
+}
+
+// isTLSVersion reports whether version is one of the TLS versions of crypto/tls.
+func isTLSVersion(version uint16) bool {
+	switch version {
+	case tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,110 @@
+package hqgohttp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// newTLSServer starts a TLS server supporting the versions from min to max only.
+func newTLSServer(t *testing.T, min, max uint16) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(tlsVersionName(r.TLS.Version)))
+	}))
+
+	server.TLS = &tls.Config{MinVersion: min, MaxVersion: max}
+	// The handshake failures are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+
+	server.StartTLS()
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// tlsVersionName returns the name of a TLS version, as tls.VersionName does from Go 1.21.
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS 1.0"
+	case tls.VersionTLS11:
+		return "TLS 1.1"
+	case tls.VersionTLS12:
+		return "TLS 1.2"
+	case tls.VersionTLS13:
+		return "TLS 1.3"
+	default:
+		return fmt.Sprintf("0x%04X", version)
+	}
+}
+
+func getTLSVersion(t *testing.T, options Options, URL string) (version string, err error) {
+	t.Helper()
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewRequest(methods.Get, URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req.SkipTLSVerify())
+	if err != nil {
+		return
+	}
+
+	return ReadBodyString(res, 64)
+}
+
+func TestMinTLSVersionTooLow(t *testing.T) {
+	t.Parallel()
+
+	server := newTLSServer(t, tls.VersionTLS10, tls.VersionTLS10)
+
+	options := *DefaultOptionsSingle
+	options.MinTLSVersion = tls.VersionTLS12
+	options.RetryWaitMin = time.Second
+
+	start := time.Now()
+
+	_, err := getTLSVersion(t, options, server.URL)
+	if !errors.Is(err, ErrTLSVersionTooLow) {
+		t.Fatalf("got %v, want ErrTLSVersionTooLow", err)
+	}
+
+	if elapsed := time.Since(start); elapsed >= options.RetryWaitMin {
+		t.Errorf("took %s, the version mismatch was retried", elapsed)
+	}
+}
+
+func TestMaxTLSVersionOnly(t *testing.T) {
+	t.Parallel()
+
+	server := newTLSServer(t, tls.VersionTLS10, tls.VersionTLS12)
+
+	options := *DefaultOptionsSingle
+	options.MaxTLSVersion = tls.VersionTLS11
+
+	version, err := getTLSVersion(t, options, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if version != "TLS 1.1" {
+		t.Errorf("negotiated %s, want TLS 1.1", version)
+	}
+}
@@ -0,0 +1,82 @@
+package hqgohttp
+
+// This file contains the OpenTelemetry tracing of requests: one client span per Do call, with an
+// event per retry, and the propagation of the trace context to servers with the traceparent header.
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts the span of a Do call when a TracerProvider is set, moving the request into
+// its context and injecting the trace context into the request headers. The returned function
+// ends the span with the outcome of the call, and moves the request back to its context.
+func (c *Client) startSpan(req *Request) (end func(res *http.Response, err error)) {
+	if c.options.TracerProvider == nil {
+		return func(*http.Response, error) {}
+	}
+
+	tracer := c.options.TracerProvider.Tracer(tracerName)
+
+	ctx, span := tracer.Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", redactedURL(req)),
+			attribute.String("server.address", req.URL.Hostname()),
+		),
+	)
+
+	original := req.Request
+
+	req.Request = original.WithContext(ctx)
+
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	return func(res *http.Response, err error) {
+		req.Request = original
+
+		span.SetAttributes(attribute.Int("http.resend_count", req.Metrics.Retries))
+
+		if res != nil {
+			span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+
+			if res.StatusCode >= 400 {
+				span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+			}
+		}
+
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		span.End()
+	}
+}
+
+// traceRetry adds a retry event to the span of the request, if any.
+func traceRetry(req *Request, attempt int) {
+	span := trace.SpanFromContext(req.Context())
+
+	if !span.IsRecording() {
+		return
+	}
+
+	span.AddEvent("retry", trace.WithAttributes(attribute.Int("http.resend_count", attempt)))
+}
+
+// redactedURL returns the URL of the request without its credentials.
+func redactedURL(req *Request) string {
+	if req.URL.User == nil {
+		return req.URL.String()
+	}
+
+	return req.URL.Redacted()
+}
+
+const tracerName = "github.com/hueristiq/hqgohttp"
@@ -0,0 +1,110 @@
+package hqgohttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// recordingTracerProvider records the outcome of the spans it starts, which are otherwise
+// no-ops.
+type recordingTracerProvider struct {
+	trace.TracerProvider
+
+	mutex sync.Mutex
+	spans []*recordingSpan
+}
+
+func (p *recordingTracerProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &recordingTracer{Tracer: p.TracerProvider.Tracer(""), provider: p}
+}
+
+type recordingTracer struct {
+	trace.Tracer
+
+	provider *recordingTracerProvider
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx, noop := t.Tracer.Start(ctx, name, options...)
+
+	span := &recordingSpan{Span: noop}
+
+	t.provider.mutex.Lock()
+	t.provider.spans = append(t.provider.spans, span)
+	t.provider.mutex.Unlock()
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+type recordingSpan struct {
+	trace.Span
+
+	ended  bool
+	status codes.Code
+	err    error
+}
+
+func (s *recordingSpan) End(...trace.SpanEndOption) {
+	s.ended = true
+}
+
+func (s *recordingSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.err = err
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) {
+	s.status = code
+}
+
+func TestSpanOfRejectedRequest(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	provider := &recordingTracerProvider{TracerProvider: trace.NewNoopTracerProvider()}
+
+	options := *DefaultOptionsSingle
+	options.TracerProvider = provider
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := NewRobotsPolicy("hqgohttp")
+
+	policy.Add(server.Listener.Addr().String(), []byte("User-agent: *\nDisallow: /"))
+
+	client.WithRobotsPolicy(policy)
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.Do(req); !errors.Is(err, ErrDisallowedByRobots) {
+		t.Fatalf("got %v, want the request disallowed", err)
+	}
+
+	if len(provider.spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(provider.spans))
+	}
+
+	if span := provider.spans[0]; !span.ended || span.status != codes.Error || !errors.Is(span.err, ErrDisallowedByRobots) {
+		t.Fatalf("got span ended %v with status %v and error %v, want it ended with the rejection", span.ended, span.status, span.err)
+	}
+
+	if _, ok := trace.SpanFromContext(req.Context()).(*recordingSpan); ok {
+		t.Fatal("want the request moved back to its context")
+	}
+}
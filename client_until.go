@@ -0,0 +1,69 @@
+package hqgohttp
+
+// This file contains code for retrying successful requests until their response satisfies a condition.
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ResponsePredicate reports whether a response satisfies the condition awaited by DoUntil.
+// The response body is buffered before the predicate is called, so it may be read freely.
+type ResponsePredicate func(resp *http.Response) (done bool, err error)
+
+// DoUntil re-issues the request, following the client's backoff schedule, until the predicate
+// reports done or the retries are exhausted. Each attempt goes through Do, so the usual
+// error-based retries apply independently to every attempt.
+//
+// The returned response body is a re-readable, in-memory copy of the body the predicate saw.
+// Bodies larger than Limits.MaxBufferedBodySize fail with ErrResponseBodyTooLarge.
+func (c *Client) DoUntil(req *Request, predicate ResponsePredicate) (res *http.Response, err error) {
+	retryMax := c.getRetryMax(req)
+
+	for i := 0; ; i++ {
+		attemptStart := c.clock.Now()
+
+		res, err = c.Do(req)
+		if err != nil {
+			return
+		}
+
+		var body []byte
+
+		body, err = bufferBody(res, c.bufferedBodyLimit())
+		if err != nil {
+			return nil, err
+		}
+
+		var done bool
+
+		done, err = predicate(res)
+		if err != nil {
+			return nil, err
+		}
+
+		if done {
+			res.Body = io.NopCloser(bytes.NewReader(body))
+
+			return
+		}
+
+		if retryMax-i <= 0 {
+			break
+		}
+
+		// Wait for the time specified by backoff then retry.
+		// If the context is cancelled however, return.
+		wait := c.backoff(i, res, c.clock.Now().Sub(attemptStart))
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-c.clock.After(wait):
+		}
+	}
+
+	return nil, fmt.Errorf("%s %s condition not met after %d attempts", req.Method, req.URL, retryMax+1)
+}
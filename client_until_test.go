@@ -0,0 +1,77 @@
+package hqgohttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestDoUntil(t *testing.T) {
+	t.Parallel()
+
+	var polls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			w.Write([]byte(strings.Repeat("x", 2048)))
+
+			return
+		}
+
+		if atomic.AddInt32(&polls, 1) < 3 {
+			w.Write([]byte("pending"))
+
+			return
+		}
+
+		w.Write([]byte("done"))
+	}))
+	defer server.Close()
+
+	options := *DefaultOptionsSingle
+	options.RetryMax = 5
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+	options.Limits.MaxBufferedBodySize = 1024
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isDone := func(res *http.Response) (bool, error) {
+		body, err := io.ReadAll(res.Body)
+
+		return string(body) == "done", err
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.DoUntil(req, isDone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body, _ := io.ReadAll(res.Body); string(body) != "done" || atomic.LoadInt32(&polls) != 3 {
+		t.Fatalf("got %q after %d polls, want done after 3", body, polls)
+	}
+
+	req, err = NewRequest(methods.Get, server.URL+"/large", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.DoUntil(req, isDone); !errors.Is(err, ErrResponseBodyTooLarge) {
+		t.Fatalf("got %v, want the body too large to buffer", err)
+	}
+}
@@ -1,14 +1,30 @@
 package hqgohttp
 
 import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	hqgoreaderutil "github.com/hueristiq/hqgoutils/reader"
 )
 
+// ContextOverride is the type of the context keys overriding client settings per request.
+//
+// Deprecated: string keys may collide with keys set by other packages. Use the
+// With* helpers, e.g. WithRetryMax, instead.
 type ContextOverride string
 
 const (
+	// RetryMax overrides Options.RetryMax for a request.
+	//
+	// Deprecated: use WithRetryMax.
 	RetryMax ContextOverride = "retry-max"
 )
 
@@ -57,3 +73,148 @@ func getReusableBodyandContentLength(rawBody interface{}) (reader *hqgoreaderuti
 
 	return
 }
+
+// bufferBody reads the whole response body into memory, closes the original body and
+// replaces it with a re-readable copy. The buffered bytes are returned so the body
+// can be reset again later. Bodies larger than limit fail with ErrResponseBodyTooLarge.
+func bufferBody(resp *http.Response, limit int64) (body []byte, err error) {
+	// Read one extra byte to find out whether the limit was hit.
+	body, err = io.ReadAll(io.LimitReader(resp.Body, limit+1))
+
+	resp.Body.Close()
+
+	if err != nil {
+		return
+	}
+
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: more than %d bytes to buffer", ErrResponseBodyTooLarge, limit)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return
+}
+
+// parseRetryAfter returns the delay of a Retry-After header value, given either in
+// seconds or as an HTTP date. ok is false for absent or invalid values.
+func parseRetryAfter(value string, now time.Time) (delay time.Duration, ok bool) {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return
+	}
+
+	if delay = date.Sub(now); delay < 0 {
+		delay = 0
+	}
+
+	return delay, true
+}
+
+// countingReadCloser wraps a body and reports the number of bytes of every read to onRead.
+type countingReadCloser struct {
+	io.ReadCloser
+	onRead func(n int64)
+}
+
+func (r *countingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+
+	if n > 0 {
+		r.onRead(int64(n))
+	}
+
+	return
+}
+
+// newRequestID returns a random (version 4) UUID.
+func newRequestID() (ID string, err error) {
+	var buf [16]byte
+
+	if _, err = rand.Read(buf[:]); err != nil {
+		return
+	}
+
+	buf[6] = (buf[6] & 0x0f) | 0x40 // version 4
+	buf[8] = (buf[8] & 0x3f) | 0x80 // variant 10
+
+	ID = fmt.Sprintf("%x-%x-%x-%x-%x", buf[0:4], buf[4:6], buf[6:8], buf[8:10], buf[10:16])
+
+	return
+}
+
+// mergeHeaders adds the headers of src missing from dst.
+func mergeHeaders(dst, src http.Header) {
+	for key, values := range src {
+		key = http.CanonicalHeaderKey(key)
+
+		if _, ok := dst[key]; ok {
+			continue
+		}
+
+		dst[key] = append([]string(nil), values...)
+	}
+}
+
+// ErrInvalidURL is returned when building a request for a URL that can't be sent.
+var ErrInvalidURL = errors.New("invalid URL")
+
+// validateURL checks the request URL has an http(s) scheme and a host, to report
+// the common mistakes, e.g. "example.com" without "https://", upfront.
+func validateURL(rawURL string, u *url.URL) error {
+	switch {
+	case u.Scheme == "" || isHostScheme(u):
+		return fmt.Errorf("%w %q: missing scheme", ErrInvalidURL, rawURL)
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Errorf("%w %q: unsupported scheme %q", ErrInvalidURL, rawURL, u.Scheme)
+	case u.Host == "":
+		return fmt.Errorf("%w %q: missing host", ErrInvalidURL, rawURL)
+	}
+
+	return nil
+}
+
+// isHostScheme reports whether the scheme of u is the host of a schemeless URL with a port,
+// e.g. "localhost:8080" or "example.com:8080/path", which url.Parse reads as an opaque URL.
+func isHostScheme(u *url.URL) bool {
+	if strings.Contains(u.Scheme, ".") {
+		return true
+	}
+
+	port, _, _ := strings.Cut(u.Opaque, "/")
+
+	if port == "" {
+		return false
+	}
+
+	for _, r := range port {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// withDefaultScheme prepends Options.DefaultScheme to schemeless URLs.
+func (c *Client) withDefaultScheme(URL string) string {
+	if c.options.DefaultScheme == "" || strings.Contains(URL, "://") {
+		return URL
+	}
+
+	return c.options.DefaultScheme + "://" + strings.TrimPrefix(URL, "//")
+}
@@ -0,0 +1,79 @@
+package hqgohttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+func TestNewRequestValidatesURL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		URL    string
+		reason string
+	}{
+		{"example.com", "missing scheme"},
+		{"example.com/path", "missing scheme"},
+		{"127.0.0.1:8080", "missing scheme"},
+		{"localhost:8080", "missing scheme"},
+		{"localhost:8080/path", "missing scheme"},
+		{"example.com:8080", "missing scheme"},
+		{"example.com:8080/path", "missing scheme"},
+		{"example.com:path", "missing scheme"},
+		{"mailto:user@example.com", "unsupported scheme"},
+		{"ftp://example.com/", "unsupported scheme"},
+		{"https://", "missing host"},
+		{"http:///path", "missing host"},
+	}
+
+	for _, tt := range tests {
+		_, err := NewRequest(methods.Get, tt.URL, nil)
+		if !errors.Is(err, ErrInvalidURL) || !strings.Contains(err.Error(), tt.reason) {
+			t.Errorf("%s: got %v, want %s", tt.URL, err, tt.reason)
+		}
+	}
+}
+
+func TestDefaultScheme(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	options := *DefaultOptionsSingle
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	localhost := "localhost:" + host[strings.LastIndex(host, ":")+1:]
+
+	for _, URL := range []string{host, localhost} {
+		if _, err = client.Get(URL); !errors.Is(err, ErrInvalidURL) || !strings.Contains(err.Error(), "missing scheme") {
+			t.Fatalf("%s: got %v, want the schemeless URL rejected", URL, err)
+		}
+	}
+
+	options.DefaultScheme = "http"
+
+	if client, err = New(&options); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, URL := range []string{host, "//" + host, localhost} {
+		res, err := client.Get(URL)
+		if err != nil {
+			t.Fatalf("%s: %v", URL, err)
+		}
+
+		res.Body.Close()
+	}
+}
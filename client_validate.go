@@ -0,0 +1,186 @@
+package hqgohttp
+
+// This file contains the validation of client options, run by New to catch misconfigurations early.
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// ErrInvalidOptions is wrapped by every error returned by Options.Validate.
+var ErrInvalidOptions = errors.New("invalid options")
+
+// Validate reports nonsensical options, joining one error per violated rule:
+//
+//   - Timeout must be positive.
+//   - RetryMax, RetryWaitMin, RetryWaitMax, RetryBudget, RespReadLimit, MaxTotalBytes,
+//     MaxConcurrentRequests, MaxConcurrentRetries, BufferSize, MaxBytesPerSecond,
+//     DrainTimeout, ConnHealthCheckInterval, MaxLineLength, MetaRefreshMaxDelay,
+//     MaxCompressionRatio, MaxRequestsPerConn, RequestDelay, RequestDelayJitter,
+//     MaxRuntime, LongPollInterval, MaxRedirectDuration and the Limits must not be
+//     negative.
+//   - RetryWaitMin must not exceed RetryWaitMax.
+//   - TimeoutAdjustFactor must be zero (the default) or within (0, 1].
+//   - MinTLSVersion and MaxTLSVersion must be zero or TLS versions of crypto/tls, MinTLSVersion
+//     not exceeding MaxTLSVersion.
+//   - BaseURLs must be absolute http(s) URLs.
+//   - IPVersion must be DualStack, IPv4Only or IPv6Only.
+//   - ForceHTTP2 and ForceHTTP10 are mutually exclusive.
+//   - ForceHTTP2 and Minimal are mutually exclusive.
+func (o *Options) Validate() error {
+	var errs []error
+
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidOptions, fmt.Sprintf(format, args...)))
+	}
+
+	if o.Timeout <= 0 {
+		invalid("Timeout must be positive, got %s", o.Timeout)
+	}
+
+	if o.RetryMax < 0 {
+		invalid("RetryMax must not be negative, got %d", o.RetryMax)
+	}
+
+	if o.RetryWaitMin < 0 {
+		invalid("RetryWaitMin must not be negative, got %s", o.RetryWaitMin)
+	}
+
+	if o.RetryWaitMax < 0 {
+		invalid("RetryWaitMax must not be negative, got %s", o.RetryWaitMax)
+	}
+
+	if o.RetryWaitMin > o.RetryWaitMax {
+		invalid("RetryWaitMin (%s) must not exceed RetryWaitMax (%s)", o.RetryWaitMin, o.RetryWaitMax)
+	}
+
+	if o.TimeoutAdjustFactor < 0 || o.TimeoutAdjustFactor > 1 {
+		invalid("TimeoutAdjustFactor must be within (0, 1], got %v", o.TimeoutAdjustFactor)
+	}
+
+	if o.RetryBudget < 0 {
+		invalid("RetryBudget must not be negative, got %v", o.RetryBudget)
+	}
+
+	if o.RespReadLimit < 0 {
+		invalid("RespReadLimit must not be negative, got %d", o.RespReadLimit)
+	}
+
+	if o.MaxTotalBytes < 0 {
+		invalid("MaxTotalBytes must not be negative, got %d", o.MaxTotalBytes)
+	}
+
+	if o.MaxConcurrentRequests < 0 {
+		invalid("MaxConcurrentRequests must not be negative, got %d", o.MaxConcurrentRequests)
+	}
+
+	if o.MaxConcurrentRetries < 0 {
+		invalid("MaxConcurrentRetries must not be negative, got %d", o.MaxConcurrentRetries)
+	}
+
+	if o.BufferSize < 0 {
+		invalid("BufferSize must not be negative, got %d", o.BufferSize)
+	}
+
+	if o.MaxBytesPerSecond < 0 {
+		invalid("MaxBytesPerSecond must not be negative, got %d", o.MaxBytesPerSecond)
+	}
+
+	if o.DrainTimeout < 0 {
+		invalid("DrainTimeout must not be negative, got %s", o.DrainTimeout)
+	}
+
+	if o.ConnHealthCheckInterval < 0 {
+		invalid("ConnHealthCheckInterval must not be negative, got %s", o.ConnHealthCheckInterval)
+	}
+
+	if o.MinTLSVersion != 0 && !isTLSVersion(o.MinTLSVersion) {
+		invalid("unknown MinTLSVersion %#x", o.MinTLSVersion)
+	}
+
+	if o.MaxTLSVersion != 0 && !isTLSVersion(o.MaxTLSVersion) {
+		invalid("unknown MaxTLSVersion %#x", o.MaxTLSVersion)
+	}
+
+	if o.MinTLSVersion != 0 && o.MaxTLSVersion != 0 && o.MinTLSVersion > o.MaxTLSVersion {
+		invalid("MinTLSVersion (%#x) must not exceed MaxTLSVersion (%#x)", o.MinTLSVersion, o.MaxTLSVersion)
+	}
+
+	if o.MaxLineLength < 0 {
+		invalid("MaxLineLength must not be negative, got %d", o.MaxLineLength)
+	}
+
+	if o.MetaRefreshMaxDelay < 0 {
+		invalid("MetaRefreshMaxDelay must not be negative, got %s", o.MetaRefreshMaxDelay)
+	}
+
+	if o.Limits.MaxRequestBodySize < 0 {
+		invalid("Limits.MaxRequestBodySize must not be negative, got %d", o.Limits.MaxRequestBodySize)
+	}
+
+	if o.Limits.MaxResponseHeaderBytes < 0 {
+		invalid("Limits.MaxResponseHeaderBytes must not be negative, got %d", o.Limits.MaxResponseHeaderBytes)
+	}
+
+	if o.Limits.MaxResponseBodySize < 0 {
+		invalid("Limits.MaxResponseBodySize must not be negative, got %d", o.Limits.MaxResponseBodySize)
+	}
+
+	if o.Limits.MaxBufferedBodySize < 0 {
+		invalid("Limits.MaxBufferedBodySize must not be negative, got %d", o.Limits.MaxBufferedBodySize)
+	}
+
+	if o.MaxCompressionRatio < 0 {
+		invalid("MaxCompressionRatio must not be negative, got %v", o.MaxCompressionRatio)
+	}
+
+	if o.MaxRequestsPerConn < 0 {
+		invalid("MaxRequestsPerConn must not be negative, got %d", o.MaxRequestsPerConn)
+	}
+
+	if o.RequestDelay < 0 {
+		invalid("RequestDelay must not be negative, got %s", o.RequestDelay)
+	}
+
+	if o.RequestDelayJitter < 0 {
+		invalid("RequestDelayJitter must not be negative, got %s", o.RequestDelayJitter)
+	}
+
+	if o.MaxRuntime < 0 {
+		invalid("MaxRuntime must not be negative, got %s", o.MaxRuntime)
+	}
+
+	if o.LongPollInterval < 0 {
+		invalid("LongPollInterval must not be negative, got %s", o.LongPollInterval)
+	}
+
+	if o.MaxRedirectDuration < 0 {
+		invalid("MaxRedirectDuration must not be negative, got %s", o.MaxRedirectDuration)
+	}
+
+	for _, baseURL := range o.BaseURLs {
+		u, err := url.Parse(baseURL)
+		if err == nil {
+			err = validateURL(baseURL, u)
+		}
+
+		if err != nil {
+			invalid("BaseURLs must be absolute http(s) URLs, got %q", baseURL)
+		}
+	}
+
+	if o.IPVersion > IPv6Only {
+		invalid("unknown IPVersion %d", o.IPVersion)
+	}
+
+	if o.ForceHTTP2 && o.ForceHTTP10 {
+		invalid("ForceHTTP2 and ForceHTTP10 are mutually exclusive")
+	}
+
+	if o.Minimal && o.ForceHTTP2 {
+		invalid("ForceHTTP2 requires the HTTP/2 client, which Minimal doesn't build")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package hqgohttp
+
+// This file contains code for attaching arbitrary state to a request, shared by the hooks it goes through.
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// requestValues is the mutable state of a request, stored in its context. Being a pointer, it
+// is shared by the shallow copies of the request made along the way, so values set by a hook
+// are seen by the next ones.
+type requestValues struct {
+	mutex  sync.RWMutex
+	values map[interface{}]interface{}
+}
+
+// SetValue attaches val to the request under key, replacing any previous value. As for context
+// values, key must be comparable and should be of an unexported type of the caller's package,
+// so keys of different packages can't collide. Values are kept in the request context, along
+// with its clones.
+func (r *Request) SetValue(key, val interface{}) {
+	checkValueKey(key)
+
+	values := r.values()
+
+	values.mutex.Lock()
+	values.values[key] = val
+	values.mutex.Unlock()
+}
+
+// GetValue returns the value attached to the request under key, or nil.
+func (r *Request) GetValue(key interface{}) interface{} {
+	return RequestValue(r.Context(), key)
+}
+
+// values returns the state of the request, attaching it to the context if needed.
+func (r *Request) values() *requestValues {
+	if values, ok := r.Context().Value(valuesKey{}).(*requestValues); ok {
+		return values
+	}
+
+	values := &requestValues{values: make(map[interface{}]interface{})}
+
+	r.WithContext(context.WithValue(r.Context(), valuesKey{}, values))
+
+	return values
+}
+
+// RequestValue returns the value attached under key to the request owning ctx, or nil. Hooks
+// read values with it, e.g. from the *http.Request given to PreSendHook, BeforeRetry and
+// RequestLogHook, or the response's Request given to ResponseLogHook and ResponseHook.
+func RequestValue(ctx context.Context, key interface{}) interface{} {
+	values, ok := ctx.Value(valuesKey{}).(*requestValues)
+	if !ok {
+		return nil
+	}
+
+	values.mutex.RLock()
+	defer values.mutex.RUnlock()
+
+	return values.values[key]
+}
+
+// SetRequestValue attaches val under key to the request owning ctx, as SetValue does, for
+// hooks only given the *http.Request. Do makes every request able to hold values, so this
+// only reports false for contexts of requests sent otherwise.
+func SetRequestValue(ctx context.Context, key, val interface{}) (ok bool) {
+	checkValueKey(key)
+
+	values, ok := ctx.Value(valuesKey{}).(*requestValues)
+	if !ok {
+		return
+	}
+
+	values.mutex.Lock()
+	values.values[key] = val
+	values.mutex.Unlock()
+
+	return
+}
+
+// checkValueKey panics on keys unusable as map keys, as context.WithValue does.
+func checkValueKey(key interface{}) {
+	if key == nil {
+		panic("nil key")
+	}
+
+	if !reflect.TypeOf(key).Comparable() {
+		panic("key is not comparable")
+	}
+}
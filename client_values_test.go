@@ -0,0 +1,80 @@
+package hqgohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+type attemptKey struct{}
+
+type callerKey struct{}
+
+func TestRequestValues(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	var logged, hooked interface{}
+
+	var caller interface{}
+
+	options := *DefaultOptionsSingle
+	options.PreSendHook = func(req *http.Request) error {
+		caller = RequestValue(req.Context(), callerKey{})
+
+		if !SetRequestValue(req.Context(), attemptKey{}, "set by PreSendHook") {
+			t.Error("PreSendHook couldn't set a value")
+		}
+
+		return nil
+	}
+	options.ResponseHook = func(res *http.Response) (*http.Response, error) {
+		hooked = RequestValue(res.Request.Context(), attemptKey{})
+
+		return res, nil
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.ResponseLogHook = func(res *http.Response) {
+		logged = RequestValue(res.Request.Context(), attemptKey{})
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.SetValue(callerKey{}, "set by the caller")
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if caller != "set by the caller" {
+		t.Errorf("PreSendHook got %v, want the caller's value", caller)
+	}
+
+	if logged != "set by PreSendHook" || hooked != "set by PreSendHook" {
+		t.Errorf("ResponseLogHook got %v and ResponseHook %v, want PreSendHook's value", logged, hooked)
+	}
+
+	if got := req.GetValue(attemptKey{}); got != "set by PreSendHook" {
+		t.Errorf("the caller got %v, want PreSendHook's value", got)
+	}
+
+	// A key of another type with the same underlying value doesn't collide
+	if got := req.GetValue(struct{}{}); got != nil {
+		t.Errorf("got %v for a foreign key, want nil", got)
+	}
+}
@@ -0,0 +1,97 @@
+package hqgohttp
+
+// This file contains code for verifying downloaded response bodies against an expected checksum.
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrChecksumMismatch is returned when reading a verified body whose checksum doesn't match the expected one.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrUnsupportedChecksum is returned for an unknown checksum algorithm.
+	ErrUnsupportedChecksum = errors.New("unsupported checksum algorithm")
+)
+
+// GetVerified is a convenience method for doing GET requests whose response body is
+// verified against an expected checksum (hex encoded) computed with algo, one of md5,
+// sha1, sha256 or sha512.
+//
+// The checksum is computed as the body is read, without buffering it. Once the body is
+// read until its end, the read returning io.EOF returns ErrChecksumMismatch instead if
+// the checksum doesn't match. Callers must therefore read the body fully and check the
+// read error before trusting the data.
+func (c *Client) GetVerified(URL, algo, expected string) (*http.Response, error) {
+	h, err := newChecksumHash(algo)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.Get(URL)
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = &verifyingReadCloser{
+		ReadCloser: res.Body,
+		hash:       h,
+		expected:   strings.ToLower(expected),
+	}
+
+	return res, nil
+}
+
+func newChecksumHash(algo string) (h hash.Hash, err error) {
+	switch strings.ToLower(algo) {
+	case "md5":
+		h = md5.New()
+	case "sha1":
+		h = sha1.New()
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedChecksum, algo)
+	}
+
+	return
+}
+
+// verifyingReadCloser hashes a body as it is read and checks the checksum on io.EOF.
+type verifyingReadCloser struct {
+	io.ReadCloser
+	hash     hash.Hash
+	expected string
+	err      error
+}
+
+func (r *verifyingReadCloser) Read(p []byte) (n int, err error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	n, err = r.ReadCloser.Read(p)
+
+	r.hash.Write(p[:n])
+
+	if errors.Is(err, io.EOF) {
+		if actual := hex.EncodeToString(r.hash.Sum(nil)); actual != r.expected {
+			err = fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, r.expected, actual)
+		}
+
+		r.err = err
+	}
+
+	return
+}
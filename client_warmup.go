@@ -0,0 +1,189 @@
+package hqgohttp
+
+// This file contains code for warming up the connection pool to a known set of hosts, so
+// that the first requests to them don't pay for dialing and TLS handshakes.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// ErrKeepAlivesDisabled is returned by WarmupHosts when the client's transport doesn't keep
+// connections alive, so they can't be pooled.
+var ErrKeepAlivesDisabled = errors.New("keep-alives are disabled")
+
+// WarmupHosts establishes perHost idle connections to the host of every URL, concurrently,
+// and returns the outcome per origin (`scheme://host`): nil once its connections are pooled,
+// the error met otherwise. URLs that don't parse are keyed as is.
+//
+// It requires a transport keeping connections alive, set with Options.SharedTransport or
+// Options.HTTPClient, e.g. DefaultHTTPPooledTransport. The transport of clients built by New
+// otherwise disables keep-alives, and all hosts fail with ErrKeepAlivesDisabled.
+//
+// The connections are opened by HEAD requests through the client's transport, so they are
+// reused by the requests that follow, without the hooks, retries and accounting of Do.
+// perHost defaults to 1 and is capped by MaxIdleConnsPerHost and MaxConnsPerHost. HTTP/2
+// hosts end up with a single multiplexed connection.
+func (c *Client) WarmupHosts(ctx context.Context, urls []string, perHost int) (errs map[string]error) {
+	perHost, pooled := c.warmupConns(perHost)
+
+	targets := make(map[string]string)
+
+	errs = make(map[string]error)
+
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err == nil {
+			err = validateURL(raw, u)
+		}
+
+		if err != nil {
+			errs[raw] = err
+
+			continue
+		}
+
+		origin := u.Scheme + "://" + u.Host
+
+		if _, ok := targets[origin]; !ok {
+			targets[origin] = raw
+		}
+	}
+
+	mutex := &sync.Mutex{}
+
+	wg := &sync.WaitGroup{}
+
+	for origin, target := range targets {
+		if !pooled {
+			errs[origin] = ErrKeepAlivesDisabled
+
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(origin, target string) {
+			defer wg.Done()
+
+			err := c.warmupHost(ctx, target, perHost)
+
+			mutex.Lock()
+			errs[origin] = err
+			mutex.Unlock()
+		}(origin, target)
+	}
+
+	wg.Wait()
+
+	return
+}
+
+// warmupHost opens n connections to the host of target with concurrent HEAD requests, each
+// held once connected until all of them are, returning the first error met.
+func (c *Client) warmupHost(ctx context.Context, target string, n int) (err error) {
+	connected := &sync.WaitGroup{}
+
+	connected.Add(n)
+
+	ready := make(chan struct{})
+
+	go func() {
+		connected.Wait()
+
+		close(ready)
+	}()
+
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- c.warmupConn(ctx, target, connected, ready)
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		if connErr := <-errs; connErr != nil && err == nil {
+			err = connErr
+		}
+	}
+
+	return
+}
+
+// warmupConn sends a HEAD request to target, holding it once connected until ready is closed,
+// and pools its connection.
+func (c *Client) warmupConn(ctx context.Context, target string, connected *sync.WaitGroup, ready <-chan struct{}) (err error) {
+	once := &sync.Once{}
+
+	arrive := func() {
+		once.Do(connected.Done)
+	}
+
+	// Don't hold the other requests if this one fails before connecting
+	defer arrive()
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(httptrace.GotConnInfo) {
+			arrive()
+
+			select {
+			case <-ready:
+			case <-ctx.Done():
+			}
+		},
+	}
+
+	req, err := NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), methods.Head, target, nil)
+	if err != nil {
+		return
+	}
+
+	res, err := c.tlsClient(req, c.HTTPClient).Do(req.Request)
+	if err != nil {
+		return
+	}
+
+	c.drainBody(req, res)
+
+	return
+}
+
+// warmupConns caps the number of connections to warm up per host to what the transport keeps,
+// reporting whether it keeps any.
+func (c *Client) warmupConns(perHost int) (n int, pooled bool) {
+	if perHost <= 0 {
+		perHost = 1
+	}
+
+	transport, ok := c.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		return perHost, true
+	}
+
+	if transport.DisableKeepAlives || transport.MaxIdleConnsPerHost < 0 {
+		return perHost, false
+	}
+
+	idle := transport.MaxIdleConnsPerHost
+
+	if idle == 0 {
+		idle = http.DefaultMaxIdleConnsPerHost
+	}
+
+	if perHost > idle {
+		perHost = idle
+	}
+
+	if transport.MaxConnsPerHost > 0 && perHost > transport.MaxConnsPerHost {
+		perHost = transport.MaxConnsPerHost
+	}
+
+	return perHost, true
+}
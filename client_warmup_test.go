@@ -0,0 +1,152 @@
+package hqgohttp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// newWarmupClient returns a client pooling the connections to the TLS server.
+func newWarmupClient(tb testing.TB, server *httptest.Server) *Client {
+	tb.Helper()
+
+	transport := DefaultHTTPPooledTransport()
+
+	trusted, _ := server.Client().Transport.(*http.Transport)
+
+	transport.TLSClientConfig = trusted.TLSClientConfig.Clone()
+
+	options := *DefaultOptionsSingle
+	options.SharedTransport = transport
+
+	client, err := New(&options)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return client
+}
+
+func TestWarmupHosts(t *testing.T) {
+	t.Parallel()
+
+	var conns int32
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.StartTLS()
+
+	defer server.Close()
+
+	client := newWarmupClient(t, server)
+
+	errs := client.WarmupHosts(context.Background(), []string{server.URL + "/a", server.URL + "/b", "example.com"}, 2)
+
+	if len(errs) != 2 || errs[server.URL] != nil || !errors.Is(errs["example.com"], ErrInvalidURL) {
+		t.Fatalf("got %v, want the server warmed up and example.com invalid", errs)
+	}
+
+	if got := atomic.LoadInt32(&conns); got != 2 {
+		t.Fatalf("got %d connections warmed up, want 2", got)
+	}
+
+	req, err := NewRequest(methods.Get, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res.Body.Close()
+
+	if got := atomic.LoadInt32(&conns); got != 2 {
+		t.Fatalf("got %d connections after a request, want the 2 warmed up", got)
+	}
+}
+
+func TestWarmupHostsDefaultClient(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	// The transport of clients built by New doesn't keep connections alive
+	for _, options := range []*Options{DefaultOptionsSingle, DefaultOptionsSpraying} {
+		client, err := New(options)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errs := client.WarmupHosts(context.Background(), []string{server.URL, server.URL + "/path"}, 1)
+
+		if len(errs) != 1 || !errors.Is(errs[server.URL], ErrKeepAlivesDisabled) {
+			t.Fatalf("got %v, want ErrKeepAlivesDisabled for %s", errs, server.URL)
+		}
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 0 {
+		t.Fatalf("got %d requests, want none", got)
+	}
+}
+
+func BenchmarkFirstRequest(b *testing.B) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	first := func(b *testing.B, warm bool) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+
+			client := newWarmupClient(b, server)
+
+			if warm {
+				if err := client.WarmupHosts(context.Background(), []string{server.URL}, 1)[server.URL]; err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			req, err := NewRequest(methods.Get, server.URL, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.StartTimer()
+
+			res, err := client.Do(req)
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			res.Body.Close()
+
+			b.StopTimer()
+
+			client.HTTPClient.CloseIdleConnections()
+		}
+	}
+
+	b.Run("cold", func(b *testing.B) {
+		first(b, false)
+	})
+
+	b.Run("warmed", func(b *testing.B) {
+		first(b, true)
+	})
+}
@@ -5,7 +5,10 @@ go 1.20
 require (
 	github.com/Mzack9999/go-http-digest-auth-client v0.6.0
 	github.com/hueristiq/hqgoutils v0.0.0-20231024005153-bd2c47932440
+	go.opentelemetry.io/otel v1.19.0
+	go.opentelemetry.io/otel/trace v1.19.0
 	golang.org/x/net v0.17.0
+	golang.org/x/sync v0.5.0
 )
 
 require golang.org/x/text v0.13.0 // indirect
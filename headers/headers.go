@@ -135,9 +135,20 @@ const (
 	Index               = "Index"
 	LargeAllocation     = "Large-Allocation"
 	Link                = "Link"
+	Prefer              = "Prefer"
 	PushPolicy          = "Push-Policy"
 	RetryAfter          = "Retry-After"
+	XRatelimitLimit     = "X-Ratelimit-Limit"
 	XRatelimitRemaining = "X-Ratelimit-Remaining"
+	XRatelimitReset     = "X-Ratelimit-Reset"
+	XRateLimitLimit     = "X-Rate-Limit-Limit"
+	XRateLimitRemaining = "X-Rate-Limit-Remaining"
+	XRateLimitReset     = "X-Rate-Limit-Reset"
+	RateLimit           = "RateLimit"
+	RateLimitLimit      = "RateLimit-Limit"
+	RateLimitPolicy     = "RateLimit-Policy"
+	RateLimitRemaining  = "RateLimit-Remaining"
+	RateLimitReset      = "RateLimit-Reset"
 	ServerTiming        = "Server-Timing"
 	Signature           = "Signature"
 	SignedHeaders       = "Signed-Headers"
@@ -145,6 +156,7 @@ const (
 	Upgrade             = "Upgrade"
 	XDNSPrefetchControl = "X-DNS-Prefetch-Control"
 	XPingback           = "X-Pingback"
+	XRequestID          = "X-Request-ID"
 	XRequestedWith      = "X-Requested-With"
 	XRobotsTag          = "X-Robots-Tag"
 	XUACompatible       = "X-UA-Compatible"
@@ -3,10 +3,17 @@ package hqgohttp
 // This file contains utility functions to create HTTP clients and transports.
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"runtime"
+	"strings"
 	"time"
+
+	"golang.org/x/net/http/httpproxy"
 )
 
 // DefaultHTTPTransport returns a new http.Transport with similar default values to
@@ -33,14 +40,10 @@ func DefaultHTTPTransport() (transport *http.Transport) {
 // time. Only use this for transports that will be re-used for the same host(s).
 func DefaultHTTPPooledTransport() (transport *http.Transport) {
 	transport = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-			DualStack: true,
-		}).DialContext,
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           DefaultDialer().DialContext,
 		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
+		IdleConnTimeout:       defaultIdleConnTimeout,
 		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
 		ForceAttemptHTTP2:     true,
@@ -71,3 +74,157 @@ func DefaultPooledClient() *http.Client {
 		Transport: DefaultHTTPPooledTransport(),
 	}
 }
+
+// DefaultDialer returns a new net.Dialer with similar default values to the
+// dialer used by http.DefaultTransport: a 30 seconds connect timeout and a
+// 30 seconds TCP keep-alive probe interval.
+func DefaultDialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: defaultTCPKeepAlive,
+		DualStack: true,
+	}
+}
+
+// newDialer returns a new net.Dialer based on DefaultDialer, tuned by the provided options.
+func newDialer(options *Options) (dialer *net.Dialer) {
+	dialer = DefaultDialer()
+
+	// A negative TCPKeepAlive disables keep-alive probes, a zero value keeps the default.
+	switch {
+	case options.TCPKeepAlive < 0:
+		dialer.KeepAlive = -1
+	case options.TCPKeepAlive > 0:
+		dialer.KeepAlive = options.TCPKeepAlive
+	}
+
+	// New reports the addresses that can't be bound to
+	if addr, err := localAddr(options); err == nil && addr != nil {
+		dialer.LocalAddr = addr
+	}
+
+	return
+}
+
+// IPVersion selects the IP address family used to dial connections.
+type IPVersion uint8
+
+const (
+	// DualStack dials over IPv4 or IPv6, whichever the host resolves to.
+	DualStack IPVersion = iota
+	// IPv4Only dials over IPv4 only.
+	IPv4Only
+	// IPv6Only dials over IPv6 only.
+	IPv6Only
+)
+
+// ErrNoAddressForIPVersion is returned when a host has no address of the IP version
+// the client is restricted to.
+var ErrNoAddressForIPVersion = errors.New("no address for the requested IP version")
+
+// newDialContext returns the dial function of dialer, restricted to the requested IP version.
+// Hosts with no address of that version fail with ErrNoAddressForIPVersion instead of
+// falling back to the other version.
+func newDialContext(dialer *net.Dialer, version IPVersion) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	var restricted string
+
+	switch version {
+	case IPv4Only:
+		restricted = "tcp4"
+	case IPv6Only:
+		restricted = "tcp6"
+	case DualStack:
+	}
+
+	if restricted == "" {
+		return dialer.DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (conn net.Conn, err error) {
+		if network == "tcp" {
+			network = restricted
+		}
+
+		conn, err = dialer.DialContext(ctx, network, addr)
+
+		var addrErr *net.AddrError
+
+		if errors.As(err, &addrErr) {
+			err = fmt.Errorf("%w: %s over %s: %w", ErrNoAddressForIPVersion, addr, network, err)
+		}
+
+		return
+	}
+}
+
+// dialContext returns the dial function of the options: the custom DialContext if any,
+// or the client's dialer restricted to the IP version.
+func dialContext(options *Options) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if options.DialContext != nil {
+		return options.DialContext
+	}
+
+	return newDialContext(newDialer(options), options.IPVersion)
+}
+
+// configureTransport applies the provided options to a transport built by the client.
+func configureTransport(transport *http.Transport, options *Options) (err error) {
+	transport.DialContext = dialContext(options)
+
+	if options.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = options.IdleConnTimeout
+	}
+
+	if options.ProxyURL != "" {
+		if transport.Proxy, err = newProxy(options.ProxyURL, options.NoProxy); err != nil {
+			return
+		}
+	}
+
+	if options.Limits.MaxResponseHeaderBytes > 0 {
+		transport.MaxResponseHeaderBytes = options.Limits.MaxResponseHeaderBytes
+	}
+
+	if options.MinTLSVersion != 0 || options.MaxTLSVersion != 0 {
+		applyTLSVersions(transport, options)
+	}
+
+	if len(options.PinnedPublicKeySHA256) > 0 {
+		pinTransport(transport, options.PinnedPublicKeySHA256)
+	}
+
+	return
+}
+
+// newProxy returns a proxy function sending all requests through proxyURL, except
+// those whose host matches one of the noProxy entries. Entries follow the NO_PROXY
+// environment variable semantics: host names match themselves and their subdomains
+// (a leading "." or "*." matches subdomains only), IP addresses and CIDR ranges match
+// the addresses they cover, "host:port" restricts the match to that port, and "*"
+// disables the proxy entirely.
+func newProxy(proxyURL string, noProxy []string) (proxy func(*http.Request) (*url.URL, error), err error) {
+	if _, err = url.Parse(proxyURL); err != nil {
+		err = fmt.Errorf("invalid proxy URL %q: %w", proxyURL, err)
+
+		return
+	}
+
+	config := &httpproxy.Config{
+		HTTPProxy:  proxyURL,
+		HTTPSProxy: proxyURL,
+		NoProxy:    strings.Join(noProxy, ","),
+	}
+
+	proxyFunc := config.ProxyFunc()
+
+	proxy = func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+
+	return
+}
+
+const (
+	defaultTCPKeepAlive    = 30 * time.Second
+	defaultIdleConnTimeout = 90 * time.Second
+)
@@ -0,0 +1,110 @@
+package hqgohttp
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp/methods"
+)
+
+// pipeListener is an in-memory listener accepting the server ends of net.Pipe connections.
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() {
+		close(l.closed)
+	})
+
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: "pipe", Net: "pipe"}
+}
+
+func (l *pipeListener) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	client, server := net.Pipe()
+
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestDialContext(t *testing.T) {
+	t.Parallel()
+
+	listener := newPipeListener()
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("in-memory " + r.Host))
+		}),
+	}
+
+	go server.Serve(listener)
+
+	defer server.Close()
+
+	var dials []string
+
+	options := *DefaultOptionsSingle
+	options.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dials = append(dials, addr)
+
+		return listener.DialContext(ctx, network, addr)
+	}
+
+	client, err := New(&options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The host doesn't resolve: only the custom dialer can reach it
+	req, err := NewRequest(methods.Get, "http://backend.invalid/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(res.Body)
+
+	res.Body.Close()
+
+	if err != nil || string(body) != "in-memory backend.invalid" {
+		t.Fatalf("got %q, %v, want %q", body, err, "in-memory backend.invalid")
+	}
+
+	if len(dials) != 1 || dials[0] != "backend.invalid:80" {
+		t.Fatalf("got dials %q, want [backend.invalid:80]", dials)
+	}
+}
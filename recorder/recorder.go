@@ -0,0 +1,332 @@
+package recorder
+
+// This file contains a record/replay transport for deterministic testing of code built on the client.
+//
+// In record mode, requests go to the network and every request/response pair is saved to a
+// cassette file. In replay mode, responses are served from the cassette, matching requests
+// on method, URL, body and headers (minus the ignored ones), without any network access.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/hueristiq/hqgohttp"
+	"github.com/hueristiq/hqgohttp/headers"
+)
+
+// Mode selects whether the recorder records or replays interactions.
+type Mode uint8
+
+const (
+	// ModeAuto replays the cassette if it exists and records a new one otherwise.
+	ModeAuto Mode = iota
+	// ModeRecord sends requests to the network and records them, overwriting the cassette.
+	ModeRecord
+	// ModeReplay serves responses from the cassette only.
+	ModeReplay
+)
+
+// ErrInteractionNotFound is returned in replay mode when no recorded interaction matches a request.
+var ErrInteractionNotFound = errors.New("no recorded interaction matches the request")
+
+// DefaultIgnoredHeaders lists the request headers ignored when matching requests by default,
+// as they usually differ between runs.
+var DefaultIgnoredHeaders = []string{
+	headers.Date,
+	headers.XRequestID,
+}
+
+// Config configures a Recorder.
+type Config struct {
+	// Mode selects whether to record or replay. Defaults to ModeAuto.
+	Mode Mode
+	// IgnoreHeaders lists the request headers left out when matching requests,
+	// every other header must match. Defaults to DefaultIgnoredHeaders.
+	IgnoreHeaders []string
+	// Transport is the transport used to reach the network in record mode.
+	// Defaults to hqgohttp.DefaultHTTPPooledTransport().
+	Transport http.RoundTripper
+	// Options configures the client returned by NewRecordingClientWithConfig.
+	// Defaults to hqgohttp.DefaultOptionsSingle.
+	Options *hqgohttp.Options
+}
+
+// Interaction is a recorded request/response pair.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper recording interactions to, or replaying them from, a cassette file.
+type Recorder struct {
+	path    string
+	mode    Mode
+	ignored map[string]bool
+	next    http.RoundTripper
+
+	mutex        sync.Mutex
+	interactions []Interaction
+	replayed     []bool
+}
+
+// New creates a Recorder backed by the cassette file at path. A nil config is the zero
+// Config.
+func New(path string, config *Config) (recorder *Recorder, err error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	recorder = &Recorder{
+		path:    path,
+		mode:    config.Mode,
+		ignored: map[string]bool{},
+		next:    config.Transport,
+	}
+
+	if recorder.next == nil {
+		recorder.next = hqgohttp.DefaultHTTPPooledTransport()
+	}
+
+	ignored := config.IgnoreHeaders
+
+	if ignored == nil {
+		ignored = DefaultIgnoredHeaders
+	}
+
+	for _, header := range ignored {
+		recorder.ignored[http.CanonicalHeaderKey(header)] = true
+	}
+
+	if recorder.mode == ModeAuto {
+		recorder.mode = ModeRecord
+
+		if _, err = os.Stat(path); err == nil {
+			recorder.mode = ModeReplay
+		}
+	}
+
+	if recorder.mode == ModeReplay {
+		var raw []byte
+
+		if raw, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal(raw, &recorder.interactions); err != nil {
+			return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+		}
+
+		recorder.replayed = make([]bool, len(recorder.interactions))
+	}
+
+	return recorder, nil
+}
+
+// NewRecordingClient returns a client recording interactions to the cassette file at
+// cassettePath if it doesn't exist yet, and replaying them from it otherwise.
+func NewRecordingClient(cassettePath string) (*hqgohttp.Client, error) {
+	return NewRecordingClientWithConfig(cassettePath, &Config{})
+}
+
+// NewRecordingClientWithConfig is like NewRecordingClient, with a custom configuration.
+// The client keeps its retry and backoff behavior, only the transport is replaced. A nil
+// config is the zero Config.
+func NewRecordingClientWithConfig(cassettePath string, config *Config) (*hqgohttp.Client, error) {
+	if config == nil {
+		config = &Config{}
+	}
+
+	recorder, err := New(cassettePath, config)
+	if err != nil {
+		return nil, err
+	}
+
+	options := hqgohttp.DefaultOptionsSingle
+
+	if config.Options != nil {
+		options = config.Options
+	}
+
+	clientOptions := *options
+
+	clientOptions.HTTPClient = &http.Client{Transport: recorder}
+	// Every request must go through the recorder
+	clientOptions.DisableHTTP2Fallback = true
+	clientOptions.ForceHTTP2 = false
+
+	return hqgohttp.New(&clientOptions)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	recorded, err := r.recordRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, &recorded)
+	}
+
+	return r.record(req, &recorded)
+}
+
+func (r *Recorder) record(req *http.Request, recorded *Request) (res *http.Response, err error) {
+	res, err = r.next.RoundTrip(req)
+	if err != nil {
+		return
+	}
+
+	body, err := io.ReadAll(res.Body)
+
+	res.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.interactions = append(r.interactions, Interaction{
+		Request: *recorded,
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     res.Header.Clone(),
+			Body:       body,
+		},
+	})
+
+	if err = r.save(); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func (r *Recorder) replay(req *http.Request, recorded *Request) (*http.Response, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	// Serve identical requests in recorded order, then keep serving the last match.
+	match := -1
+
+	for i := range r.interactions {
+		if !r.matches(&r.interactions[i].Request, recorded) {
+			continue
+		}
+
+		match = i
+
+		if !r.replayed[i] {
+			break
+		}
+	}
+
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, req.URL)
+	}
+
+	r.replayed[match] = true
+
+	response := r.interactions[match].Response
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", response.StatusCode, http.StatusText(response.StatusCode)),
+		StatusCode:    response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        response.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(response.Body)),
+		ContentLength: int64(len(response.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) matches(recorded, req *Request) bool {
+	if recorded.Method != req.Method || recorded.URL != req.URL || !bytes.Equal(recorded.Body, req.Body) {
+		return false
+	}
+
+	return r.headersMatch(recorded.Header, req.Header) && r.headersMatch(req.Header, recorded.Header)
+}
+
+// headersMatch reports whether every non-ignored header of a has the same values in b.
+func (r *Recorder) headersMatch(a, b http.Header) bool {
+	for key, values := range a {
+		if r.ignored[http.CanonicalHeaderKey(key)] {
+			continue
+		}
+
+		other := b.Values(key)
+
+		if len(values) != len(other) {
+			return false
+		}
+
+		for i := range values {
+			if values[i] != other[i] {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// recordRequest snapshots the request, restoring its body so it can still be sent.
+func (r *Recorder) recordRequest(req *http.Request) (recorded Request, err error) {
+	recorded = Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+
+	if recorded.Body, err = io.ReadAll(req.Body); err != nil {
+		return
+	}
+
+	req.Body.Close()
+
+	req.Body = io.NopCloser(bytes.NewReader(recorded.Body))
+
+	return
+}
+
+// save writes all the interactions to the cassette. The caller must hold the mutex.
+func (r *Recorder) save() error {
+	raw, err := json.MarshalIndent(r.interactions, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.path, raw, 0o600)
+}
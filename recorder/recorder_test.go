@@ -0,0 +1,91 @@
+package recorder
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hueristiq/hqgohttp"
+)
+
+func TestNewNilConfig(t *testing.T) {
+	t.Parallel()
+
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	if _, err := New(cassette, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewRecordingClientWithConfig(cassette, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	t.Parallel()
+
+	var hits int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Write([]byte("recorded " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	get := func(client *hqgohttp.Client, path string) (body string, err error) {
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			return
+		}
+
+		return hqgohttp.ReadBodyString(res, 1024)
+	}
+
+	recording, err := NewRecordingClient(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = get(recording, "/a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The cassette now exists, a new client replays it without reaching the server
+	replaying, err := NewRecordingClient(cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := get(replaying, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if body != "recorded /a" {
+		t.Errorf("replayed %q, want %q", body, "recorded /a")
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Errorf("server got %d requests, want 1", got)
+	}
+
+	options := *hqgohttp.DefaultOptionsSingle
+	options.RetryMax = 0
+
+	strict, err := NewRecordingClientWithConfig(cassette, &Config{Mode: ModeReplay, Options: &options})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = get(strict, "/b"); !errors.Is(err, ErrInteractionNotFound) {
+		t.Errorf("got %v, want ErrInteractionNotFound", err)
+	}
+}